@@ -0,0 +1,522 @@
+// Package arena plays games between registered engines in-process against a local ruleset.
+package arena
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/BattlesnakeOfficial/rules"
+)
+
+// Game types.
+const (
+	Standard = "standard"
+	Royale   = "royale"
+	Solo     = "solo"
+)
+
+// Turn is the state of a game turn provided to an engine.
+type Turn struct {
+	GameID   string
+	Turn     int
+	Board    *rules.BoardState // Copy of the board, engines may modify it.
+	SnakeIdx int               // Index of the engine's snake.
+	Hazards  []rules.Point
+	Names    map[string]string // Engine names by snake ID
+	TimedOut []bool            // Snakes whose previous move timed out by index
+
+	// Logf logs engine diagnostics if set, e.g. when replaying a game.
+	Logf func(format string, args ...interface{})
+}
+
+// Options configure a series of games.
+type Options struct {
+	Engines   []string       // Engine names, the players of each game are sampled from these.
+	Players   int            // Snakes per game, defaults to the number of engines.
+	Games     int            // Number of games, defaults to 1.
+	Width     int32          // Defaults to 11.
+	Height    int32          // Defaults to 11.
+	GameTypes []string       // Game types played in turn, defaults to standard.
+	Seed      int64          // Seed of the first game, incremented per game.
+	MaxTurns  int            // Maximum turns per game, defaults to 1000.
+	Timeout   time.Duration  // Move timeout after which the snake continues straight, defaults to 500ms.
+	Workers   int            // Games played concurrently, defaults to 1.
+	Skip      map[int64]bool // Seeds of games to skip, e.g. played before resuming.
+}
+
+// Game is a single game configuration.
+type Game struct {
+	ID       string
+	Type     string
+	Seed     int64
+	Width    int32
+	Height   int32
+	Engines  []string // Engine names by snake index
+	Seeds    []int64  // Engine random seeds by snake index, defaults to seeds derived from the game seed.
+	MaxTurns int
+	Timeout  time.Duration
+}
+
+// Result is the outcome of a game.
+type Result struct {
+	ID       string        `json:"id"`
+	Type     string        `json:"type"`
+	Seed     int64         `json:"seed"`
+	Width    int32         `json:"width"`
+	Height   int32         `json:"height"`
+	Engines  []string      `json:"engines"`  // Engine names by snake index
+	Seeds    []int64       `json:"seeds"`    // Engine random seeds by snake index, the ruleset is seeded by Seed
+	Moves    []string      `json:"moves"`    // Moves by turn, a character per snake, see encodeMoves
+	Winner   int           `json:"winner"`   // Winning snake index, -1 if none
+	Turns    int           `json:"turns"`    // Turns played
+	Deaths   []int         `json:"deaths"`   // Turn each snake was eliminated, -1 if alive
+	Causes   []string      `json:"causes"`   // Elimination cause of each snake
+	Lengths  []int         `json:"lengths"`  // Final length of each snake
+	Timeouts []int         `json:"timeouts"` // Timed out moves of each snake
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"` // Error that aborted the game
+}
+
+// WinnerName returns the engine name of the winner or an empty string if none.
+func (r Result) WinnerName() string {
+	if r.Winner < 0 {
+		return ""
+	}
+	return r.Engines[r.Winner]
+}
+
+// Run plays the games defined by the options concurrently, calling fn with each result
+// in order of completion. Calls to fn are not concurrent. It returns early if the context
+// is cancelled or fn returns an error, discarding games interrupted by cancellation.
+func Run(ctx context.Context, o Options, fn func(Result) error) error {
+	if len(o.Engines) == 0 {
+		return fmt.Errorf("no engines")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	games := make(chan Game)
+	go func() {
+		defer close(games)
+		for i := 0; i < defaultInt(o.Games, 1); i++ {
+			g := o.game(i)
+			if o.Skip[g.Seed] {
+				continue
+			}
+
+			select {
+			case games <- g:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make(chan Result)
+	var wg sync.WaitGroup
+	for w := 0; w < defaultInt(o.Workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for g := range games {
+				res := Play(ctx, g)
+				if ctx.Err() != nil {
+					// Interrupted
+					continue
+				}
+				results <- res
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var err error
+	for res := range results {
+		if err != nil {
+			continue
+		}
+		if err = fn(res); err != nil {
+			cancel()
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// game returns the i'th game of the options.
+func (o Options) game(i int) Game {
+	players := defaultInt(o.Players, len(o.Engines))
+	if o.gameType(i) == Solo {
+		players = 1
+	}
+
+	// Sample players from the engines, repeating engines if there are more players.
+	rnd := rand.New(rand.NewSource(o.Seed + int64(i)))
+	perm := rnd.Perm(len(o.Engines))
+	var names []string
+	for j := 0; j < players; j++ {
+		names = append(names, o.Engines[perm[j%len(perm)]])
+	}
+
+	return o.Game(i, names)
+}
+
+// Game returns the i'th game of the options played by the engines.
+func (o Options) Game(i int, engines []string) Game {
+	seed := o.Seed + int64(i)
+
+	return Game{
+		ID:       fmt.Sprintf("game-%d", seed),
+		Type:     o.gameType(i),
+		Seed:     seed,
+		Width:    defaultInt32(o.Width, rules.BoardSizeMedium),
+		Height:   defaultInt32(o.Height, rules.BoardSizeMedium),
+		Engines:  engines,
+		MaxTurns: defaultInt(o.MaxTurns, 1000),
+		Timeout:  o.Timeout,
+	}
+}
+
+// gameType returns the type of the i'th game.
+func (o Options) gameType(i int) string {
+	if len(o.GameTypes) == 0 {
+		return Standard
+	}
+	return o.GameTypes[i%len(o.GameTypes)]
+}
+
+// Play plays the game and returns its result.
+func Play(ctx context.Context, g Game) (res Result) {
+	t0 := time.Now()
+
+	l := len(g.Engines)
+	res = Result{
+		ID:       g.ID,
+		Type:     g.Type,
+		Seed:     g.Seed,
+		Width:    g.Width,
+		Height:   g.Height,
+		Engines:  g.Engines,
+		Seeds:    engineSeeds(g),
+		Winner:   -1,
+		Deaths:   make([]int, l),
+		Causes:   make([]string, l),
+		Lengths:  make([]int, l),
+		Timeouts: make([]int, l),
+	}
+	for i := range res.Deaths {
+		res.Deaths[i] = -1
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			res.Error = fmt.Sprintf("panic: %v", r)
+		}
+		res.Duration = time.Since(t0)
+	}()
+
+	if err := play(ctx, g, &res); err != nil {
+		res.Error = err.Error()
+	}
+
+	return res
+}
+
+// play plays the game updating the result.
+func play(ctx context.Context, g Game, res *Result) error {
+	if g.Timeout == 0 {
+		g.Timeout = time.Millisecond * 500
+	}
+
+	ids, names, players, err := newPlayers(g.Engines, res.Seeds)
+	if err != nil {
+		return err
+	}
+
+	ruleset, royale, err := newRuleset(g)
+	if err != nil {
+		return err
+	}
+
+	b, err := ruleset.CreateInitialBoardState(g.Width, g.Height, ids)
+	if err != nil {
+		return err
+	}
+
+	var hazards []rules.Point
+	timedOut := make([]bool, len(players))
+	for res.Turns < g.MaxTurns {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if over, err := ruleset.IsGameOver(b); err != nil {
+			return err
+		} else if over {
+			break
+		}
+
+		var moves []rules.SnakeMove
+		for i, e := range players {
+			if b.Snakes[i].EliminatedCause != "" {
+				continue
+			}
+
+			move, ok, err := timedMove(ctx, e, Turn{
+				GameID:   g.ID,
+				Turn:     res.Turns,
+				Board:    copyBoard(b),
+				SnakeIdx: i,
+				Hazards:  append([]rules.Point(nil), hazards...),
+				Names:    names,
+				TimedOut: append([]bool(nil), timedOut...),
+			}, g.Timeout)
+			if err != nil {
+				return err
+			}
+
+			timedOut[i] = !ok
+			if timedOut[i] {
+				// The ruleset continues straight if the move is empty.
+				res.Timeouts[i]++
+				move = ""
+			}
+
+			moves = append(moves, rules.SnakeMove{ID: ids[i], Move: move})
+		}
+		res.Moves = append(res.Moves, encodeMoves(ids, moves, timedOut))
+
+		res.Turns++
+		b, hazards, err = nextBoard(ruleset, royale, b, moves, res.Turns)
+		if err != nil {
+			return err
+		}
+
+		for i, s := range b.Snakes {
+			if s.EliminatedCause != "" && res.Deaths[i] == -1 {
+				res.Deaths[i] = res.Turns
+				res.Causes[i] = s.EliminatedCause
+			}
+		}
+	}
+
+	var alive []int
+	for i, s := range b.Snakes {
+		res.Lengths[i] = len(s.Body)
+		if s.EliminatedCause == "" {
+			alive = append(alive, i)
+		}
+	}
+	if len(alive) == 1 && len(b.Snakes) > 1 {
+		res.Winner = alive[0]
+	}
+
+	for i, e := range players {
+		if ender, ok := e.(Ender); ok {
+			ender.End(ctx, Turn{
+				GameID:   g.ID,
+				Turn:     res.Turns,
+				Board:    copyBoard(b),
+				SnakeIdx: i,
+				Hazards:  hazards,
+				Names:    names,
+			})
+		}
+	}
+
+	return nil
+}
+
+// newPlayers returns the snake IDs, the engine names by snake ID and the engines
+// constructed with their seeds.
+func newPlayers(engines []string, seeds []int64) ([]string, map[string]string, []Engine, error) {
+	var (
+		ids     []string
+		names   = make(map[string]string)
+		players []Engine
+	)
+	for i, name := range engines {
+		e, err := NewEngine(name, seeds[i])
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		id := fmt.Sprintf("snake-%d", i)
+		ids = append(ids, id)
+		names[id] = name
+		players = append(players, e)
+	}
+
+	return ids, names, players, nil
+}
+
+// engineSeeds returns the engine seeds of the game, derived from the game seed if not set.
+func engineSeeds(g Game) []int64 {
+	if len(g.Seeds) == len(g.Engines) {
+		return g.Seeds
+	}
+
+	rnd := rand.New(rand.NewSource(g.Seed))
+	res := make([]int64, len(g.Engines))
+	for i := range res {
+		res[i] = rnd.Int63()
+	}
+
+	return res
+}
+
+// nextBoard returns the board and hazards after applying the moves of the turn.
+func nextBoard(ruleset rules.Ruleset, royale *rules.RoyaleRuleset, b *rules.BoardState,
+	moves []rules.SnakeMove, turn int) (*rules.BoardState, []rules.Point, error) {
+	if royale != nil {
+		royale.Turn = int32(turn)
+	}
+
+	b, err := ruleset.CreateNextBoardState(b, moves)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if royale == nil {
+		return b, nil, nil
+	}
+
+	return b, royale.OutOfBounds, nil
+}
+
+// seededRuleset places snakes and spawns food with the game's own random source, since
+// the rules package uses the global source that concurrent games and engines share. The
+// wrapped ruleset doesn't spawn food.
+type seededRuleset struct {
+	rules.Ruleset
+	rnd             *rand.Rand
+	foodSpawnChance int32
+	minimumFood     int32
+}
+
+func (r seededRuleset) CreateInitialBoardState(width, height int32, ids []string) (*rules.BoardState, error) {
+	b := &rules.BoardState{Width: width, Height: height, Snakes: make([]rules.Snake, len(ids))}
+	for i, id := range ids {
+		b.Snakes[i] = rules.Snake{ID: id, Health: rules.SnakeMaxHealth}
+	}
+
+	if err := placeSnakes(r.rnd, b); err != nil {
+		return nil, err
+	}
+	if err := placeFood(r.rnd, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (r seededRuleset) CreateNextBoardState(b *rules.BoardState, moves []rules.SnakeMove) (*rules.BoardState, error) {
+	b, err := r.Ruleset.CreateNextBoardState(b, moves)
+	if err != nil {
+		return nil, err
+	}
+
+	maybeSpawnFood(r.rnd, b, r.foodSpawnChance, r.minimumFood)
+
+	return b, nil
+}
+
+// cpus limits concurrent engine moves to the number of CPUs, so that concurrent games
+// don't starve engines of their time budget.
+var cpus = make(chan struct{}, runtime.NumCPU())
+
+// timedMove returns the engine's move once a CPU is available and false if it errored
+// or exceeded the timeout, which is also the engine's context deadline. An error is only
+// returned if the context is done.
+func timedMove(ctx context.Context, e Engine, t Turn, timeout time.Duration) (string, bool, error) {
+	select {
+	case cpus <- struct{}{}:
+	case <-ctx.Done():
+		return "", false, ctx.Err()
+	}
+	defer func() { <-cpus }()
+
+	t0 := time.Now()
+	mctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	move, err := e.Move(mctx, t)
+	if ctx.Err() != nil {
+		return "", false, ctx.Err()
+	}
+
+	return move, err == nil && time.Since(t0) <= timeout, nil
+}
+
+// newRuleset returns the seeded ruleset of the game type and the royale ruleset if applicable.
+func newRuleset(g Game) (rules.Ruleset, *rules.RoyaleRuleset, error) {
+	var standard rules.StandardRuleset // Food is spawned by seededRuleset.
+
+	var (
+		ruleset rules.Ruleset
+		royale  *rules.RoyaleRuleset
+	)
+	switch g.Type {
+	case Standard, "":
+		ruleset = &standard
+	case Royale:
+		royale = &rules.RoyaleRuleset{
+			StandardRuleset:   standard,
+			Seed:              g.Seed,
+			ShrinkEveryNTurns: 25,
+			DamagePerTurn:     15,
+		}
+		ruleset = royale
+	case Solo:
+		ruleset = &rules.SoloRuleset{StandardRuleset: standard}
+	default:
+		return nil, nil, fmt.Errorf("unknown game type: %s", g.Type)
+	}
+
+	return seededRuleset{
+		Ruleset:         ruleset,
+		rnd:             rand.New(rand.NewSource(g.Seed)),
+		foodSpawnChance: 15,
+		minimumFood:     1,
+	}, royale, nil
+}
+
+// copyBoard returns a deep copy of the board.
+func copyBoard(b *rules.BoardState) *rules.BoardState {
+	res := &rules.BoardState{
+		Height: b.Height,
+		Width:  b.Width,
+		Food:   append([]rules.Point(nil), b.Food...),
+		Snakes: make([]rules.Snake, len(b.Snakes)),
+	}
+	for i, s := range b.Snakes {
+		s.Body = append([]rules.Point(nil), s.Body...)
+		res.Snakes[i] = s
+	}
+	return res
+}
+
+func defaultInt(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+func defaultInt32(v, def int32) int32 {
+	if v == 0 {
+		return def
+	}
+	return v
+}
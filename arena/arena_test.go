@@ -0,0 +1,150 @@
+package arena
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	opts := Options{
+		Engines:   []string{"random", "random", "random"},
+		Players:   2,
+		Games:     6,
+		Width:     7,
+		Height:    7,
+		GameTypes: []string{Standard, Royale, Solo},
+		Seed:      42,
+	}
+
+	var results []Result
+	err := Run(context.Background(), opts, func(r Result) error {
+		results = append(results, r)
+		return nil
+	})
+	jtest.RequireNil(t, err)
+	require.Len(t, results, 6)
+
+	for i, r := range results {
+		require.Empty(t, r.Error)
+		require.Equal(t, opts.Seed+int64(i), r.Seed)
+		require.Equal(t, opts.GameTypes[i%3], r.Type)
+		require.Greater(t, r.Turns, 0)
+
+		if r.Type == Solo {
+			require.Len(t, r.Engines, 1)
+			require.Equal(t, -1, r.Winner)
+			require.Equal(t, r.Turns, r.Deaths[0])
+			continue
+		}
+
+		require.Len(t, r.Engines, 2)
+		for i, d := range r.Deaths {
+			if i == r.Winner {
+				require.Equal(t, -1, d)
+			} else {
+				require.NotEqual(t, -1, d)
+				require.NotEmpty(t, r.Causes[i])
+			}
+		}
+	}
+}
+
+func TestRunConcurrent(t *testing.T) {
+	opts := Options{
+		Engines: []string{"random", "random"},
+		Games:   10,
+		Width:   7,
+		Height:  7,
+		Seed:    100,
+		Workers: 4,
+		Skip:    map[int64]bool{101: true, 105: true},
+	}
+
+	seeds := make(map[int64]bool)
+	err := Run(context.Background(), opts, func(r Result) error {
+		require.Empty(t, r.Error)
+		require.False(t, seeds[r.Seed])
+		seeds[r.Seed] = true
+		return nil
+	})
+	jtest.RequireNil(t, err)
+	require.Len(t, seeds, 8)
+	for seed := range opts.Skip {
+		require.False(t, seeds[seed])
+	}
+}
+
+func TestRunReproducible(t *testing.T) {
+	run := func(workers int) map[int64]Result {
+		opts := Options{
+			Engines:   []string{"random", "random"},
+			Games:     8,
+			Width:     11,
+			Height:    11,
+			GameTypes: []string{Standard, Royale},
+			Seed:      200,
+			Workers:   workers,
+		}
+
+		res := make(map[int64]Result)
+		err := Run(context.Background(), opts, func(r Result) error {
+			require.Empty(t, r.Error)
+			res[r.Seed] = r
+			return nil
+		})
+		jtest.RequireNil(t, err)
+		return res
+	}
+
+	// Consume the global source concurrently, it must not affect the games.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				rand.Intn(100)
+			}
+		}
+	}()
+
+	serial := run(1)
+	concurrent := run(4)
+	require.Len(t, concurrent, len(serial))
+	for seed, r := range serial {
+		c := concurrent[seed]
+		require.Equal(t, r.Moves, c.Moves)
+		require.Equal(t, r.Turns, c.Turns)
+		require.Equal(t, r.Deaths, c.Deaths)
+		require.Equal(t, r.Lengths, c.Lengths)
+		require.Equal(t, r.Winner, c.Winner)
+	}
+}
+
+func TestUnknown(t *testing.T) {
+	r := Play(context.Background(), Game{Engines: []string{"unknown"}})
+	require.Equal(t, "unknown engine: unknown", r.Error)
+
+	r = Play(context.Background(), Game{Type: "unknown", Engines: []string{"random"}})
+	require.Equal(t, "unknown game type: unknown", r.Error)
+}
+
+func TestTimeout(t *testing.T) {
+	Register("error", func(*rand.Rand) Engine {
+		return EngineFunc(func(context.Context, Turn) (string, error) {
+			return "", context.DeadlineExceeded
+		})
+	})
+
+	r := Play(context.Background(), Game{Type: Solo, Seed: 1, Width: 7, Height: 7, Engines: []string{"error"}, MaxTurns: 100})
+	require.Empty(t, r.Error)
+	require.Equal(t, r.Turns, r.Timeouts[0])
+	require.Equal(t, "wall-collision", r.Causes[0])
+	require.Less(t, r.Turns, 7)
+}
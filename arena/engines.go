@@ -0,0 +1,146 @@
+package arena
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/BattlesnakeOfficial/rules"
+
+	"github.com/corverroos/bsnake/board"
+	"github.com/corverroos/bsnake/mcts"
+)
+
+// Engine selects the moves of a snake.
+type Engine interface {
+	// Move returns the move of the snake at t.SnakeIdx.
+	Move(ctx context.Context, t Turn) (string, error)
+}
+
+// Ender is optionally implemented by engines that keep per game state.
+type Ender interface {
+	// End is called once the game ended.
+	End(ctx context.Context, t Turn)
+}
+
+// EngineFunc is a stateless engine.
+type EngineFunc func(ctx context.Context, t Turn) (string, error)
+
+func (fn EngineFunc) Move(ctx context.Context, t Turn) (string, error) {
+	return fn(ctx, t)
+}
+
+var engines = struct {
+	sync.Mutex
+	m map[string]func(*rand.Rand) Engine
+}{m: make(map[string]func(*rand.Rand) Engine)}
+
+// Register registers an engine constructor by name. A new engine is constructed for
+// each snake in each game with its own random source, see Result.Seeds.
+func Register(name string, fn func(rnd *rand.Rand) Engine) {
+	engines.Lock()
+	defer engines.Unlock()
+
+	engines.m[name] = fn
+}
+
+// Engines returns the names of the registered engines.
+func Engines() []string {
+	engines.Lock()
+	defer engines.Unlock()
+
+	var res []string
+	for name := range engines.m {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// NewEngine returns a new engine by name with a random source of the seed.
+func NewEngine(name string, seed int64) (Engine, error) {
+	engines.Lock()
+	defer engines.Unlock()
+
+	fn, ok := engines.m[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown engine: %s", name)
+	}
+
+	return fn(rand.New(rand.NewSource(seed))), nil
+}
+
+// MCTSEngine returns a stateless engine searching with a copy of the options.
+func MCTSEngine(o mcts.Opts) func(*rand.Rand) Engine {
+	return func(rnd *rand.Rand) Engine {
+		return EngineFunc(func(ctx context.Context, t Turn) (string, error) {
+			o := copyOpts(o, rnd, t)
+			return mcts.SelectMove(ctx, t.Board, t.Hazards, t.SnakeIdx, &o)
+		})
+	}
+}
+
+// mxEngine returns a stateless engine searching minimax trees with a copy of the options.
+func mxEngine(o mcts.Opts) func(*rand.Rand) Engine {
+	return func(rnd *rand.Rand) Engine {
+		return EngineFunc(func(ctx context.Context, t Turn) (string, error) {
+			o := copyOpts(o, rnd, t)
+			return mcts.SelectMx(ctx, t.Board, t.Hazards, t.SnakeIdx, &o)
+		})
+	}
+}
+
+// MinimaxEngine returns a stateless engine searching alpha-beta minimax trees with a copy
+// of the options, see mcts.SelectMinimax.
+func MinimaxEngine(o mcts.Opts) func(*rand.Rand) Engine {
+	return func(rnd *rand.Rand) Engine {
+		return EngineFunc(func(ctx context.Context, t Turn) (string, error) {
+			o := copyOpts(o, rnd, t)
+			return mcts.SelectMinimax(ctx, t.Board, t.Hazards, t.SnakeIdx, &o)
+		})
+	}
+}
+
+// copyOpts returns a copy of the options searching with the random source and
+// logging to the turn's diagnostics logger if set.
+func copyOpts(o mcts.Opts, rnd *rand.Rand, t Turn) mcts.Opts {
+	o.Rand = rnd
+	if t.Logf != nil {
+		o.SetLogf(t.Logf)
+	}
+	return o
+}
+
+// randomEngine selects random rational moves.
+func randomEngine(rnd *rand.Rand) Engine {
+	return EngineFunc(func(_ context.Context, t Turn) (string, error) {
+		var moves []string
+		for _, move := range board.Moves {
+			if board.IsRationalMove(t.Board, t.SnakeIdx, move) {
+				moves = append(moves, move)
+			}
+		}
+		if len(moves) == 0 {
+			return rules.MoveUp, nil
+		}
+		return moves[rnd.Intn(len(moves))], nil
+	})
+}
+
+func init() {
+	Register("random", randomEngine)
+	Register("v1", MCTSEngine(mcts.OptsV1))
+	Register("v2", MCTSEngine(mcts.OptsV2))
+	Register("v3", MCTSEngine(mcts.OptsV3))
+	Register("v4", MCTSEngine(mcts.OptsV4))
+	Register("v5", MCTSEngine(mcts.OptsV5))
+	Register("mx0", MinimaxEngine(mcts.OptsMx0))
+	Register("mx1", MinimaxEngine(mcts.OptsMx1))
+	Register("mx2", mxEngine(mcts.OptsV4))
+	Register("mx3", mxEngine(mcts.OptsV3))
+	Register("mx4", mxEngine(mcts.OptsV2))
+	Register("mx5", mxEngine(mcts.OptsV5))
+}
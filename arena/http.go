@@ -0,0 +1,186 @@
+package arena
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/BattlesnakeOfficial/rules"
+)
+
+// HTTPEngines returns the names of the snakes served at the snake server url,
+// see HTTPEngine.
+func HTTPEngines(ctx context.Context, url string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("snakes status: %d", resp.StatusCode)
+	}
+
+	var res []string
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// HTTPEngine returns an engine constructor that requests moves from a snake server
+// over the Battlesnake API, the url excluding the /move suffix. The game timeout of a
+// move request is the time until the context deadline if any, otherwise 500ms.
+// The random source is ignored.
+func HTTPEngine(url string, timeout time.Duration) func(*rand.Rand) Engine {
+	return func(*rand.Rand) Engine {
+		return &httpEngine{
+			url:    url,
+			client: &http.Client{Timeout: timeout},
+		}
+	}
+}
+
+type httpEngine struct {
+	url    string
+	client *http.Client
+}
+
+func (e *httpEngine) Move(ctx context.Context, t Turn) (string, error) {
+	var res struct {
+		Move string `json:"move"`
+	}
+	if err := e.post(ctx, "move", t, &res); err != nil {
+		return "", err
+	}
+	return res.Move, nil
+}
+
+func (e *httpEngine) End(ctx context.Context, t Turn) {
+	_ = e.post(ctx, "end", t, nil)
+}
+
+// post posts the turn's game request to the endpoint, decoding the response into res if not nil.
+func (e *httpEngine) post(ctx context.Context, endpoint string, t Turn, res interface{}) error {
+	gr := newGameRequest(t)
+	if deadline, ok := ctx.Deadline(); ok {
+		gr.Game.Timeout = int32(time.Until(deadline).Milliseconds())
+	}
+
+	b, err := json.Marshal(gr)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url+endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s status: %d", endpoint, resp.StatusCode)
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(res)
+}
+
+type coord struct {
+	X int32 `json:"x"`
+	Y int32 `json:"y"`
+}
+
+type battlesnake struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name"`
+	Health  int32   `json:"health"`
+	Body    []coord `json:"body"`
+	Head    coord   `json:"head"`
+	Length  int     `json:"length"`
+	Latency string  `json:"latency"`
+}
+
+type gameRequest struct {
+	Game struct {
+		ID      string `json:"id"`
+		Timeout int32  `json:"timeout"`
+	} `json:"game"`
+	Turn  int `json:"turn"`
+	Board struct {
+		Height  int32         `json:"height"`
+		Width   int32         `json:"width"`
+		Food    []coord       `json:"food"`
+		Hazards []coord       `json:"hazards,omitempty"`
+		Snakes  []battlesnake `json:"snakes"`
+	} `json:"board"`
+	You battlesnake `json:"you"`
+}
+
+// newGameRequest returns the Battlesnake API request of the turn including only alive snakes.
+func newGameRequest(t Turn) gameRequest {
+	var req gameRequest
+	req.Game.ID = t.GameID
+	req.Game.Timeout = 500
+	req.Turn = t.Turn
+	req.Board.Height = t.Board.Height
+	req.Board.Width = t.Board.Width
+	req.Board.Food = toCoords(t.Board.Food)
+	req.Board.Hazards = toCoords(t.Hazards)
+
+	for i, s := range t.Board.Snakes {
+		if s.EliminatedCause != "" && i != t.SnakeIdx {
+			continue
+		}
+
+		bs := battlesnake{
+			ID:      s.ID,
+			Name:    t.Names[s.ID],
+			Health:  s.Health,
+			Body:    toCoords(s.Body),
+			Length:  len(s.Body),
+			Latency: "100",
+		}
+		if i < len(t.TimedOut) && t.TimedOut[i] {
+			bs.Latency = "0"
+		}
+		if len(bs.Body) > 0 {
+			bs.Head = bs.Body[0]
+		}
+		if i == t.SnakeIdx {
+			req.You = bs
+		}
+		if s.EliminatedCause == "" {
+			req.Board.Snakes = append(req.Board.Snakes, bs)
+		}
+	}
+
+	return req
+}
+
+func toCoords(pl []rules.Point) []coord {
+	res := make([]coord, 0, len(pl))
+	for _, p := range pl {
+		res = append(res, coord{X: p.X, Y: p.Y})
+	}
+	return res
+}
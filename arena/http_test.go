@@ -0,0 +1,80 @@
+package arena
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPEngine(t *testing.T) {
+	var (
+		moves int
+		ends  int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req gameRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "game-3", req.Game.ID)
+		require.Equal(t, "snake-0", req.You.ID)
+		require.Equal(t, "remote", req.You.Name)
+
+		switch r.URL.Path {
+		case "/snake/move":
+			moves++
+			_, _ = w.Write([]byte(`{"move":"up"}`))
+		case "/snake/end":
+			ends++
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	Register("remote", HTTPEngine(srv.URL+"/snake/", time.Second))
+
+	r := Play(context.Background(), Game{ID: "game-3", Type: Solo, Width: 7, Height: 7, Engines: []string{"remote"}, MaxTurns: 100})
+	require.Empty(t, r.Error)
+	require.Equal(t, r.Turns, moves)
+	require.Equal(t, 1, ends)
+	require.Equal(t, 0, r.Timeouts[0])
+	require.Equal(t, "wall-collision", r.Causes[0])
+}
+
+func TestHTTPEngines(t *testing.T) {
+	var timeout int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`["mx5","v5"]`))
+		case "/v5/move":
+			var req gameRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			timeout = req.Game.Timeout
+			_, _ = w.Write([]byte(`{"move":"up"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	names, err := HTTPEngines(context.Background(), srv.URL+"/")
+	require.NoError(t, err)
+	require.Equal(t, []string{"mx5", "v5"}, names)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	Register("remote-v5", HTTPEngine(srv.URL+"/v5/", 0))
+	e, err := NewEngine("remote-v5", 0)
+	require.NoError(t, err)
+
+	_, err = e.Move(ctx, Turn{Board: &rules.BoardState{Width: 7, Height: 7, Snakes: []rules.Snake{{ID: "snake-0"}}}})
+	require.NoError(t, err)
+	require.InDelta(t, 2000, timeout, 100)
+}
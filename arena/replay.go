@@ -0,0 +1,191 @@
+package arena
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/BattlesnakeOfficial/rules"
+)
+
+// Encoded move characters, see encodeMoves.
+const (
+	moveDead     = '-'
+	moveTimeout  = '.'
+	moveStraight = '?'
+)
+
+var moveChars = map[string]byte{
+	rules.MoveUp:    'u',
+	rules.MoveDown:  'd',
+	rules.MoveLeft:  'l',
+	rules.MoveRight: 'r',
+}
+
+// encodeMoves returns the moves of a turn as a character per snake: the first letter of
+// the move, '-' if eliminated, '.' if timed out or '?' if invalid. The ruleset continues
+// straight if timed out or invalid.
+func encodeMoves(ids []string, moves []rules.SnakeMove, timedOut []bool) string {
+	res := make([]byte, len(ids))
+	for i, id := range ids {
+		res[i] = moveDead
+		for _, m := range moves {
+			if m.ID != id {
+				continue
+			}
+
+			if timedOut[i] {
+				res[i] = moveTimeout
+			} else if c, ok := moveChars[m.Move]; ok {
+				res[i] = c
+			} else {
+				res[i] = moveStraight
+			}
+		}
+	}
+
+	return string(res)
+}
+
+// decodeMoves returns the moves of the encoded turn, the moves by snake index, empty
+// if straight or eliminated, and the snakes that timed out.
+func decodeMoves(ids []string, encoded string) ([]rules.SnakeMove, []string, []bool, error) {
+	if len(encoded) != len(ids) {
+		return nil, nil, nil, fmt.Errorf("invalid encoded moves: %q", encoded)
+	}
+
+	var moves []rules.SnakeMove
+	recorded := make([]string, len(ids))
+	timedOut := make([]bool, len(ids))
+	for i, id := range ids {
+		c := encoded[i]
+		switch c {
+		case moveDead:
+			continue
+		case moveTimeout:
+			timedOut[i] = true
+		case moveStraight:
+		default:
+			for move, mc := range moveChars {
+				if mc == c {
+					recorded[i] = move
+				}
+			}
+			if recorded[i] == "" {
+				return nil, nil, nil, fmt.Errorf("invalid encoded moves: %q", encoded)
+			}
+		}
+
+		moves = append(moves, rules.SnakeMove{ID: id, Move: recorded[i]})
+	}
+
+	return moves, recorded, timedOut, nil
+}
+
+// Replayed is a snake's move of a replayed turn.
+type Replayed struct {
+	Turn     Turn          // Turn provided to the engine, the board is before the moves.
+	Recorded string        // Move played in the game, empty if the snake continued straight.
+	Move     string        // Move of the engine when replayed.
+	Duration time.Duration // Duration of the replayed move.
+	Err      error         // Error of the replayed move.
+}
+
+// Replay replays the game of the result turn for turn from its seeds and recorded moves.
+// It asks each alive snake's engine for its move every turn, so engine random sources
+// and state advance as in the game, but discards the moves before the from turn. From
+// the turn onwards, it logs diagnostics to logf if not nil and calls fn with the replayed
+// move. Engines bounded by time may replay other moves than recorded, the game follows
+// the recorded moves.
+func Replay(ctx context.Context, r Result, from int, logf func(string, ...interface{}), fn func(Replayed) error) error {
+	if len(r.Moves) != r.Turns {
+		return fmt.Errorf("game %s: moves not recorded", r.ID)
+	}
+
+	g := Game{
+		ID:      r.ID,
+		Type:    r.Type,
+		Seed:    r.Seed,
+		Width:   r.Width,
+		Height:  r.Height,
+		Engines: r.Engines,
+		Seeds:   r.Seeds,
+	}
+
+	ids, names, players, err := newPlayers(g.Engines, engineSeeds(g))
+	if err != nil {
+		return err
+	}
+
+	ruleset, royale, err := newRuleset(g)
+	if err != nil {
+		return err
+	}
+
+	b, err := ruleset.CreateInitialBoardState(g.Width, g.Height, ids)
+	if err != nil {
+		return err
+	}
+
+	var hazards []rules.Point
+	timedOut := make([]bool, len(players))
+	for turn, encoded := range r.Moves {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		moves, recorded, next, err := decodeMoves(ids, encoded)
+		if err != nil {
+			return err
+		}
+
+		for i, e := range players {
+			if b.Snakes[i].EliminatedCause != "" {
+				continue
+			}
+
+			t := Turn{
+				GameID:   g.ID,
+				Turn:     turn,
+				Board:    copyBoard(b),
+				SnakeIdx: i,
+				Hazards:  append([]rules.Point(nil), hazards...),
+				Names:    names,
+				TimedOut: append([]bool(nil), timedOut...),
+			}
+			if turn >= from {
+				t.Logf = logf
+			}
+
+			t0 := time.Now()
+			move, err := e.Move(ctx, t)
+			d := time.Since(t0)
+			if turn < from {
+				// Only advance the engine.
+				continue
+			}
+
+			// Provide the unmodified board.
+			t.Board = copyBoard(b)
+
+			err = fn(Replayed{
+				Turn:     t,
+				Recorded: recorded[i],
+				Move:     move,
+				Duration: d,
+				Err:      err,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		timedOut = next
+		b, hazards, err = nextBoard(ruleset, royale, b, moves, turn+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
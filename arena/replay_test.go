@@ -0,0 +1,90 @@
+package arena
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	opts := Options{
+		Engines:   []string{"random", "random", "random"},
+		Games:     6,
+		Width:     7,
+		Height:    7,
+		GameTypes: []string{Standard, Royale},
+		Seed:      7,
+		Workers:   3,
+	}
+
+	run := func() map[int64]Result {
+		res := make(map[int64]Result)
+		err := Run(context.Background(), opts, func(r Result) error {
+			r.Duration = 0
+			res[r.Seed] = r
+			return nil
+		})
+		jtest.RequireNil(t, err)
+		return res
+	}
+
+	// Concurrent games are reproducible.
+	results := run()
+	require.Equal(t, results, run())
+
+	for _, r := range results {
+		require.Len(t, r.Seeds, 3)
+		require.Len(t, r.Moves, r.Turns)
+
+		var n int
+		err := Replay(context.Background(), r, 0, nil, func(rep Replayed) error {
+			n++
+			require.Equal(t, rep.Recorded, rep.Move)
+			require.Equal(t, r.Moves[rep.Turn.Turn][rep.Turn.SnakeIdx], moveChars[rep.Move])
+			return nil
+		})
+		jtest.RequireNil(t, err)
+
+		// Engines advance from the first turn, so later turns replay as recorded.
+		from := r.Turns / 2
+		err = Replay(context.Background(), r, from, nil, func(rep Replayed) error {
+			require.GreaterOrEqual(t, rep.Turn.Turn, from)
+			require.Equal(t, rep.Recorded, rep.Move)
+			return nil
+		})
+		jtest.RequireNil(t, err)
+
+		var alive int
+		for _, d := range r.Deaths {
+			if d == -1 {
+				d = r.Turns
+			}
+			alive += d
+		}
+		require.Equal(t, alive, n)
+	}
+}
+
+func TestEncodeMoves(t *testing.T) {
+	ids := []string{"a", "b", "c", "d"}
+	moves := []rules.SnakeMove{
+		{ID: "a", Move: rules.MoveUp},
+		{ID: "b", Move: ""},
+		{ID: "d", Move: "invalid"},
+	}
+
+	encoded := encodeMoves(ids, moves, []bool{false, true, true, false})
+	require.Equal(t, "u.-?", encoded)
+
+	decoded, recorded, timedOut, err := decodeMoves(ids, encoded)
+	jtest.RequireNil(t, err)
+	require.Equal(t, []rules.SnakeMove{{ID: "a", Move: rules.MoveUp}, {ID: "b"}, {ID: "d"}}, decoded)
+	require.Equal(t, []string{rules.MoveUp, "", "", ""}, recorded)
+	require.Equal(t, []bool{false, true, false, false}, timedOut)
+
+	_, _, _, err = decodeMoves(ids, "ux-d")
+	require.Error(t, err)
+}
@@ -0,0 +1,159 @@
+package arena
+
+import (
+	"math/rand"
+
+	"github.com/BattlesnakeOfficial/rules"
+)
+
+// placeSnakes places the snakes at the fixed start positions of the known board sizes in
+// random order, otherwise at random even unoccupied points, like the rules package.
+func placeSnakes(rnd *rand.Rand, b *rules.BoardState) error {
+	var points []rules.Point
+	if knownBoardSize(b) {
+		mn, md, mx := int32(1), (b.Width-1)/2, b.Width-2
+		points = []rules.Point{
+			{X: mn, Y: mn}, {X: mn, Y: md}, {X: mn, Y: mx}, {X: md, Y: mn},
+			{X: md, Y: mx}, {X: mx, Y: mn}, {X: mx, Y: md}, {X: mx, Y: mx},
+		}
+		if len(b.Snakes) > len(points) {
+			return rules.ErrorTooManySnakes
+		}
+		rnd.Shuffle(len(points), func(i, j int) {
+			points[i], points[j] = points[j], points[i]
+		})
+	}
+
+	for i := range b.Snakes {
+		p := rules.Point{}
+		if points != nil {
+			p = points[i]
+		} else {
+			var even []rules.Point
+			for _, p := range unoccupiedPoints(b, true) {
+				if (p.X+p.Y)%2 == 0 {
+					even = append(even, p)
+				}
+			}
+			if len(even) == 0 {
+				return rules.ErrorNoRoomForSnake
+			}
+			p = even[rnd.Intn(len(even))]
+		}
+
+		for j := 0; j < rules.SnakeStartSize; j++ {
+			b.Snakes[i].Body = append(b.Snakes[i].Body, p)
+		}
+	}
+
+	return nil
+}
+
+// placeFood places the initial food of the known board sizes diagonally next to each
+// snake and in the center, otherwise at a random unoccupied point per snake, like the
+// rules package.
+func placeFood(rnd *rand.Rand, b *rules.BoardState) error {
+	if !knownBoardSize(b) {
+		spawnFood(rnd, b, int32(len(b.Snakes)))
+		return nil
+	}
+
+	for _, s := range b.Snakes {
+		head := s.Body[0]
+		var points []rules.Point
+		for _, p := range []rules.Point{
+			{X: head.X - 1, Y: head.Y - 1}, {X: head.X - 1, Y: head.Y + 1},
+			{X: head.X + 1, Y: head.Y - 1}, {X: head.X + 1, Y: head.Y + 1},
+		} {
+			if !containsPoint(b.Food, p) {
+				points = append(points, p)
+			}
+		}
+		if len(points) == 0 {
+			return rules.ErrorNoRoomForFood
+		}
+		b.Food = append(b.Food, points[rnd.Intn(len(points))])
+	}
+
+	center := rules.Point{X: (b.Width - 1) / 2, Y: (b.Height - 1) / 2}
+	if !containsPoint(unoccupiedPoints(b, true), center) {
+		return rules.ErrorNoRoomForFood
+	}
+	b.Food = append(b.Food, center)
+
+	return nil
+}
+
+// maybeSpawnFood spawns food up to the minimum or else one food by chance, like the
+// rules package.
+func maybeSpawnFood(rnd *rand.Rand, b *rules.BoardState, spawnChance, minimum int32) {
+	if n := int32(len(b.Food)); n < minimum {
+		spawnFood(rnd, b, minimum-n)
+	} else if spawnChance > 0 && int32(rnd.Intn(100)) < spawnChance {
+		spawnFood(rnd, b, 1)
+	}
+}
+
+// spawnFood spawns n food at random unoccupied points not next to a head.
+func spawnFood(rnd *rand.Rand, b *rules.BoardState, n int32) {
+	for i := int32(0); i < n; i++ {
+		points := unoccupiedPoints(b, false)
+		if len(points) > 0 {
+			b.Food = append(b.Food, points[rnd.Intn(len(points))])
+		}
+	}
+}
+
+// unoccupiedPoints returns the points without food or alive snakes, also excluding the
+// points next to heads unless includePossibleMoves, in x then y order.
+func unoccupiedPoints(b *rules.BoardState, includePossibleMoves bool) []rules.Point {
+	occupied := make(map[rules.Point]bool)
+	for _, p := range b.Food {
+		occupied[p] = true
+	}
+	for _, s := range b.Snakes {
+		if s.EliminatedCause != rules.NotEliminated {
+			continue
+		}
+		for i, p := range s.Body {
+			occupied[p] = true
+			if i == 0 && !includePossibleMoves {
+				occupied[rules.Point{X: p.X - 1, Y: p.Y}] = true
+				occupied[rules.Point{X: p.X + 1, Y: p.Y}] = true
+				occupied[rules.Point{X: p.X, Y: p.Y - 1}] = true
+				occupied[rules.Point{X: p.X, Y: p.Y + 1}] = true
+			}
+		}
+	}
+
+	var res []rules.Point
+	for x := int32(0); x < b.Width; x++ {
+		for y := int32(0); y < b.Height; y++ {
+			if p := (rules.Point{X: x, Y: y}); !occupied[p] {
+				res = append(res, p)
+			}
+		}
+	}
+	return res
+}
+
+// knownBoardSize returns true if the board has one of the standard square sizes.
+func knownBoardSize(b *rules.BoardState) bool {
+	if b.Width != b.Height {
+		return false
+	}
+	switch b.Width {
+	case rules.BoardSizeSmall, rules.BoardSizeMedium, rules.BoardSizeLarge:
+		return true
+	}
+	return false
+}
+
+func containsPoint(points []rules.Point, p rules.Point) bool {
+	for _, q := range points {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+// Command bench benchmarks the registered arena engines or the snakes of a snake server.
+// The "puzzles" command runs every engine on every puzzle of the suite and prints the pass
+// rate matrix. It exits with a non-zero code if a must-pass puzzle failed for a must engine,
+// see puzzle.MustPass. The must-pass check of the default must engines also runs as part of
+// "go test ./puzzle". Only the arena engines are benched in process; snakes that are only
+// served, e.g. v0, v5p, v5o, mx5o and tuned, are benched with -server.
+// The "board" command converts a ".board.txt" file to a Battlesnake API request and a request
+// file to a text board, see boardtxt.Parse. The "import" command converts a turn of a saved
+// engine game export to a request fixture and its text board from the perspective of a snake.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/corverroos/bsnake/arena"
+	"github.com/corverroos/bsnake/boardtxt"
+	"github.com/corverroos/bsnake/export"
+	"github.com/corverroos/bsnake/puzzle"
+)
+
+func main() {
+	var (
+		suite   = flag.String("suite", "testdata/puzzles.json", "puzzle suite file")
+		engines = flag.String("engines", "", "comma separated engine names, default all arena engines except random")
+		server  = flag.String("server", "", "snake server url, e.g. http://localhost:8080, benchmarks its snakes instead")
+		tags    = flag.String("tags", "", "comma separated tags, only puzzles with any of the tags are run")
+		runs    = flag.Int("runs", 1, "runs of each engine on each puzzle")
+		must    = flag.String("must", "v5,mx5", "comma separated engines that must pass must-pass puzzles")
+		turn    = flag.Int("turn", -1, "imported turn, default the last")
+		snake   = flag.String("snake", "", "ID or name of the imported snake")
+		output  = flag.String("output", "", "imported fixture path without extension, default the next testdata/input-NNN")
+		width   = flag.Int("width", 11, "board width of exports without game info")
+		height  = flag.Int("height", 11, "board height of exports without game info")
+	)
+	flag.Parse()
+
+	switch flag.Arg(0) {
+	case "puzzles":
+	case "board":
+		if err := convert(flag.Arg(1)); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(2)
+		}
+		return
+	case "import":
+		err := importExport(flag.Arg(1), *turn, *snake, *output, int32(*width), int32(*height))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(2)
+		}
+		return
+	default:
+		fmt.Println("Usage: bench [flags] puzzles|board <file>|import <export>")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	defer cancel()
+
+	names, err := engineNames(ctx, *server)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+	if *engines != "" {
+		names = strings.Split(*engines, ",")
+	}
+
+	puzzles, err := puzzle.Load(*suite)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	if *tags != "" {
+		puzzles = filter(puzzles, strings.Split(*tags, ","))
+	}
+
+	m, err := puzzle.Run(ctx, names, puzzles, *runs)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(3)
+	}
+
+	fmt.Print(m)
+
+	if failed := m.Failed(puzzle.MustPass, strings.Split(*must, ",")); len(failed) > 0 {
+		fmt.Printf("Failed %s puzzles: %s\n", puzzle.MustPass, strings.Join(failed, ", "))
+		os.Exit(1)
+	}
+}
+
+// engineNames returns the arena engines except random, or registers all the snakes of the
+// server as engines by name and returns them.
+func engineNames(ctx context.Context, server string) ([]string, error) {
+	if server == "" {
+		var res []string
+		for _, name := range arena.Engines() {
+			if name != "random" {
+				res = append(res, name)
+			}
+		}
+		return res, nil
+	}
+
+	server = strings.TrimSuffix(server, "/")
+	names, err := arena.HTTPEngines(ctx, server+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		arena.Register(name, arena.HTTPEngine(server+"/"+name+"/", 0))
+	}
+
+	return names, nil
+}
+
+// convert prints the request of the text board file or the text board of the request file.
+func convert(path string) error {
+	if path == "" {
+		return fmt.Errorf("missing board file")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(path, ".txt") {
+		g, err := boardtxt.Parse(string(b))
+		if err != nil {
+			return err
+		}
+
+		req, err := g.Request()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(req))
+		return nil
+	}
+
+	g, err := boardtxt.FromRequest(b)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(boardtxt.Render(g))
+	return nil
+}
+
+// importExport writes the request fixture and text board of the turn of the game export
+// from the perspective of the snake.
+func importExport(path string, turn int, snake, output string, width, height int32) error {
+	if path == "" || snake == "" {
+		return fmt.Errorf("usage: bench -snake <id> [-turn <turn>] [-output <path>] import <export>")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	e, err := export.Parse(b)
+	if err != nil {
+		return err
+	}
+	if e.Width == 0 || e.Height == 0 {
+		e.Width, e.Height = width, height
+	}
+
+	g, err := e.Game(turn, snake)
+	if err != nil {
+		return err
+	}
+
+	req, err := g.Request()
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output, err = nextInput()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(output+".json", req, 0o644); err != nil {
+		return err
+	}
+
+	text := boardtxt.Render(g)
+	if err := os.WriteFile(output+".board.txt", []byte(text), 0o644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported turn %d of %s [snake=%s, output=%s.json]\n%s", g.Turn, path, snake, output, text)
+
+	return nil
+}
+
+// nextInput returns the path of the next unused testdata input fixture.
+func nextInput() (string, error) {
+	for i := 1; ; i++ {
+		path := fmt.Sprintf("testdata/input-%03d", i)
+		if _, err := os.Stat(path + ".json"); os.IsNotExist(err) {
+			return path, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+}
+
+// filter returns the puzzles with any of the tags.
+func filter(puzzles []puzzle.Puzzle, tags []string) []puzzle.Puzzle {
+	var res []puzzle.Puzzle
+	for _, p := range puzzles {
+		for _, tag := range tags {
+			if p.HasTag(tag) {
+				res = append(res, p)
+				break
+			}
+		}
+	}
+	return res
+}
@@ -8,8 +8,12 @@ import (
 
 var Moves = []string{"up", "down", "right", "left"}
 
-func RandMoves() []string {
-	return moveperms[rand.Intn(perms)]
+// RandMoves returns a random permutation of the moves from the source, defaulting to the global source.
+func RandMoves(rnd *rand.Rand) []string {
+	if rnd == nil {
+		return moveperms[rand.Intn(perms)]
+	}
+	return moveperms[rnd.Intn(perms)]
 }
 
 func GenMoveSet(board *rules.BoardState) [][]string {
@@ -67,11 +71,16 @@ func IsRationalMove(board *rules.BoardState, snakeIdx int, move string) bool {
 	return true
 }
 
+// IsLoosingH2H returns true if the move could result in a head-to-head with
+// an equal or longer snake.
 func IsLoosingH2H(board *rules.BoardState, snakeIdx int, move string) bool {
 	next := MovePoint(board.Snakes[snakeIdx].Body[0], move)
 
 	for i := 0; i < len(board.Snakes); i++ {
-		if i == snakeIdx || len(board.Snakes[i].Body) < len(board.Snakes[snakeIdx].Body) {
+		if i == snakeIdx || board.Snakes[i].EliminatedCause != "" {
+			continue
+		}
+		if len(board.Snakes[i].Body) < len(board.Snakes[snakeIdx].Body) {
 			continue
 		}
 		if Distance(next, board.Snakes[i].Body[0]) == 1 {
@@ -82,6 +91,61 @@ func IsLoosingH2H(board *rules.BoardState, snakeIdx int, move string) bool {
 	return false
 }
 
+// PruneLoosingH2H returns the move sets that do not result in a possible
+// loosing head-to-head for the snake at snakeIdx. All move sets are returned
+// if there are no alternatives.
+func PruneLoosingH2H(board *rules.BoardState, snakeIdx int, moveSet [][]string) [][]string {
+	if board.Snakes[snakeIdx].EliminatedCause != "" {
+		return moveSet
+	}
+
+	res := make([][]string, 0, len(moveSet))
+	for _, moves := range moveSet {
+		if IsLoosingH2H(board, snakeIdx, moves[snakeIdx]) {
+			continue
+		}
+		res = append(res, moves)
+	}
+
+	if len(res) == 0 {
+		return moveSet
+	}
+
+	return res
+}
+
+// MoveSnake returns a copy of the board with only the snake at snakeIdx moved.
+// The snake grows and is fed if it moves onto food. Other snakes and food are shared.
+func MoveSnake(board *rules.BoardState, snakeIdx int, move string) *rules.BoardState {
+	res := *board
+	res.Snakes = append([]rules.Snake(nil), board.Snakes...)
+
+	s := &res.Snakes[snakeIdx]
+	next := MovePoint(s.Body[0], move)
+
+	body := make([]rules.Point, 0, len(s.Body)+1)
+	body = append(body, next)
+	body = append(body, s.Body...)
+
+	var fed bool
+	for _, f := range board.Food {
+		if f == next {
+			fed = true
+			break
+		}
+	}
+
+	if fed {
+		s.Health = 100
+	} else {
+		body = body[:len(body)-1]
+		s.Health--
+	}
+	s.Body = body
+
+	return &res
+}
+
 func MovePoint(p rules.Point, move string) rules.Point {
 	switch move {
 	case "up":
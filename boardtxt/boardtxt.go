@@ -0,0 +1,615 @@
+// Package boardtxt parses and renders the ".board.txt" text format of board positions,
+// used to write test fixtures by hand, see Parse.
+package boardtxt
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/BattlesnakeOfficial/rules"
+)
+
+// Grid characters, snakes are letters, see Parse.
+const (
+	empty  = '.'
+	food   = '*'
+	hazard = '░'
+	you    = 'Y'
+	other  = 'S'
+)
+
+// defaultTimeout is the move timeout in milliseconds of games without one.
+const defaultTimeout = 500
+
+// Game is a board position from the perspective of a snake.
+type Game struct {
+	ID      string
+	Ruleset string // Ruleset name, e.g. "royale", empty if unknown.
+	Timeout int32  // Move timeout in milliseconds, defaults to 500.
+	Turn    int
+	Board   *rules.BoardState
+	Hazards []rules.Point
+	You     int               // Index of the "Y" snake, -1 if none.
+	Names   map[string]string // Snake names by ID, defaults to the IDs.
+}
+
+// snakeLine is a parsed "snake:" header line.
+type snakeLine struct {
+	Letter rune
+	At     *rules.Point
+	ID     string
+	Health int32
+	Body   *string // Body moves from the head, traced on the grid if nil.
+}
+
+// Parse returns the game of the text board. The board is a grid of rows from the top
+// (highest y) to the bottom row of the characters:
+//
+//	.  empty
+//	*  food
+//	░  hazard
+//	Y  head of you, y its body
+//	S  head of another snake, s its body, any other letter may be used to tell snakes apart
+//
+// The optional header lines preceding the grid are:
+//
+//	game: <id>
+//	ruleset: <name>
+//	timeout: <ms>
+//	turn: <turn>
+//	snake: <letter> [at=x,y] [id=<id>] [health=<health>] [body=<moves>]
+//	food: x,y ...     food below snakes
+//	hazards: x,y ...  hazards below snakes or food
+//
+// Snake lines match the head of the letter at x,y or else the heads of the letter from the top row
+// in order. The body moves (u, d, l, r or "." if stacked) lead from the head over the cells of
+// its lowercase letter to the tail, bodies without moves are traced on the grid. Snakes are ordered by header line followed by the heads
+// without a line from the top row. Health defaults to 100 and the timeout to 500ms.
+func Parse(text string) (Game, error) {
+	g := Game{You: -1, Timeout: defaultTimeout}
+
+	var (
+		lines   []snakeLine
+		extra   = make(map[rune][]rules.Point)
+		rows    [][]rune
+		inGrid  bool
+		lineNum int
+	)
+	for _, line := range strings.Split(text, "\n") {
+		lineNum++
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		i := strings.Index(line, ":")
+		if inGrid || i < 0 {
+			inGrid = true
+			rows = append(rows, []rune(line))
+			continue
+		}
+
+		key, val := line[:i], strings.TrimSpace(line[i+1:])
+		var err error
+		switch key {
+		case "game":
+			g.ID = val
+		case "ruleset":
+			g.Ruleset = val
+		case "timeout":
+			var timeout int
+			timeout, err = strconv.Atoi(val)
+			g.Timeout = int32(timeout)
+		case "turn":
+			g.Turn, err = strconv.Atoi(val)
+		case "snake":
+			var l snakeLine
+			l, err = parseSnakeLine(val)
+			lines = append(lines, l)
+		case "food":
+			extra[food], err = parsePoints(val)
+		case "hazards":
+			extra[hazard], err = parsePoints(val)
+		default:
+			err = fmt.Errorf("unknown header: %s", key)
+		}
+		if err != nil {
+			return Game{}, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+	}
+
+	if len(rows) == 0 {
+		return Game{}, fmt.Errorf("empty grid")
+	}
+
+	width, height := len(rows[0]), len(rows)
+	g.Board = &rules.BoardState{Width: int32(width), Height: int32(height)}
+
+	// Grid cells by point and the heads of each letter from the top row.
+	cells := make(map[rules.Point]rune)
+	heads := make(map[rune][]rules.Point)
+	var order []rules.Point
+	for r, row := range rows {
+		if len(row) != width {
+			return Game{}, fmt.Errorf("row %d: width %d != %d", r, len(row), width)
+		}
+		for x, c := range row {
+			p := rules.Point{X: int32(x), Y: int32(height - 1 - r)}
+			cells[p] = c
+			switch {
+			case c == empty:
+			case c == food:
+				g.Board.Food = append(g.Board.Food, p)
+			case c == hazard:
+				g.Hazards = append(g.Hazards, p)
+			case unicode.IsUpper(c):
+				heads[c] = append(heads[c], p)
+				order = append(order, p)
+			case unicode.IsLower(c):
+			default:
+				return Game{}, fmt.Errorf("row %d: unknown character: %q", r, c)
+			}
+		}
+	}
+	g.Board.Food = append(g.Board.Food, extra[food]...)
+	g.Hazards = append(g.Hazards, extra[hazard]...)
+	sortPoints(g.Board.Food)
+	sortPoints(g.Hazards)
+
+	if len(heads[you]) > 1 {
+		return Game{}, fmt.Errorf("multiple %c heads", you)
+	}
+
+	// Match the header lines with heads, those at x,y first.
+	matched := make(map[rules.Point]bool)
+	lineHeads := make([]rules.Point, len(lines))
+	for i, l := range lines {
+		if l.At == nil {
+			continue
+		}
+		if cells[*l.At] != l.Letter || matched[*l.At] {
+			return Game{}, fmt.Errorf("snake %c: no head at %d,%d", l.Letter, l.At.X, l.At.Y)
+		}
+		matched[*l.At] = true
+		lineHeads[i] = *l.At
+	}
+	for i, l := range lines {
+		if l.At != nil {
+			continue
+		}
+		var found bool
+		for _, p := range heads[l.Letter] {
+			if !matched[p] {
+				matched[p] = true
+				lineHeads[i] = p
+				found = true
+				break
+			}
+		}
+		if !found {
+			return Game{}, fmt.Errorf("snake %c: no head", l.Letter)
+		}
+	}
+	for _, p := range order {
+		if !matched[p] {
+			lines = append(lines, snakeLine{Letter: cells[p], Health: 100})
+			lineHeads = append(lineHeads, p)
+		}
+	}
+
+	// Explicit bodies first, since traced bodies skip the cells of other snakes.
+	claimed := make(map[rules.Point]bool)
+	g.Board.Snakes = make([]rules.Snake, len(lines))
+	for _, explicit := range []bool{true, false} {
+		for i, l := range lines {
+			if (l.Body != nil) != explicit {
+				continue
+			}
+
+			var (
+				body []rules.Point
+				err  error
+			)
+			if explicit {
+				body, err = followBody(cells, lineHeads[i], *l.Body)
+			} else {
+				body, err = traceBody(cells, claimed, lineHeads[i])
+			}
+			if err != nil {
+				return Game{}, fmt.Errorf("snake %c at %d,%d: %w", l.Letter, lineHeads[i].X, lineHeads[i].Y, err)
+			}
+			for _, p := range body {
+				claimed[p] = true
+			}
+
+			id := l.ID
+			if id == "" && l.Letter == you {
+				id = "you"
+			} else if id == "" {
+				id = fmt.Sprintf("snake-%d", i)
+			}
+
+			g.Board.Snakes[i] = rules.Snake{ID: id, Body: body, Health: l.Health}
+			if l.Letter == you {
+				g.You = i
+			}
+		}
+	}
+
+	for _, p := range order {
+		delete(cells, p)
+	}
+	for p, c := range cells {
+		if unicode.IsLower(c) && !claimed[p] {
+			return Game{}, fmt.Errorf("body without snake at %d,%d", p.X, p.Y)
+		}
+	}
+
+	return g, nil
+}
+
+// Render returns the text board of the game, see Parse. Snakes are drawn over food and
+// hazards, heads over bodies. Snakes without a body only have a header line.
+func Render(g Game) string {
+	b := g.Board
+	grid := make([][]rune, b.Height)
+	for y := range grid {
+		grid[y] = []rune(strings.Repeat(string(empty), int(b.Width)))
+	}
+	inBounds := func(p rules.Point) bool {
+		return p.X >= 0 && p.Y >= 0 && p.X < b.Width && p.Y < b.Height
+	}
+
+	for _, p := range g.Hazards {
+		grid[p.Y][p.X] = hazard
+	}
+	for _, p := range b.Food {
+		grid[p.Y][p.X] = food
+	}
+	for i, s := range b.Snakes {
+		for j, p := range s.Body {
+			if j > 0 && inBounds(p) {
+				grid[p.Y][p.X] = unicode.ToLower(letter(g, i))
+			}
+		}
+	}
+	for i, s := range b.Snakes {
+		if len(s.Body) > 0 && inBounds(s.Body[0]) {
+			grid[s.Body[0].Y][s.Body[0].X] = letter(g, i)
+		}
+	}
+
+	var sb strings.Builder
+	if g.ID != "" {
+		fmt.Fprintf(&sb, "game: %s\n", g.ID)
+	}
+	if g.Ruleset != "" {
+		fmt.Fprintf(&sb, "ruleset: %s\n", g.Ruleset)
+	}
+	if g.Timeout != 0 && g.Timeout != defaultTimeout {
+		fmt.Fprintf(&sb, "timeout: %d\n", g.Timeout)
+	}
+	fmt.Fprintf(&sb, "turn: %d\n", g.Turn)
+	for i, s := range b.Snakes {
+		if len(s.Body) == 0 {
+			fmt.Fprintf(&sb, "snake: %c id=%s health=%d body=\n", letter(g, i), s.ID, s.Health)
+			continue
+		}
+		head := s.Body[0]
+		fmt.Fprintf(&sb, "snake: %c at=%d,%d id=%s health=%d body=%s\n",
+			letter(g, i), head.X, head.Y, s.ID, s.Health, bodyMoves(s.Body))
+	}
+
+	// Food and hazards hidden below snakes or food.
+	hidden := func(points []rules.Point, c rune) []string {
+		var res []string
+		for _, p := range points {
+			if grid[p.Y][p.X] != c {
+				res = append(res, fmt.Sprintf("%d,%d", p.X, p.Y))
+			}
+		}
+		return res
+	}
+	if h := hidden(b.Food, food); len(h) > 0 {
+		fmt.Fprintf(&sb, "food: %s\n", strings.Join(h, " "))
+	}
+	if h := hidden(g.Hazards, hazard); len(h) > 0 {
+		fmt.Fprintf(&sb, "hazards: %s\n", strings.Join(h, " "))
+	}
+
+	for y := len(grid) - 1; y >= 0; y-- {
+		sb.WriteString(string(grid[y]))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// FromRequest returns the game of the Battlesnake API move request JSON.
+func FromRequest(b []byte) (Game, error) {
+	var req struct {
+		Game struct {
+			ID      string
+			Ruleset struct {
+				Name string
+			}
+			Timeout int32
+		}
+		Turn  int
+		Board struct {
+			rules.BoardState
+			Hazards []rules.Point
+		}
+		You struct {
+			ID string
+		}
+	}
+	if err := json.Unmarshal(b, &req); err != nil {
+		return Game{}, err
+	}
+
+	g := Game{
+		ID:      req.Game.ID,
+		Ruleset: req.Game.Ruleset.Name,
+		Timeout: req.Game.Timeout,
+		Turn:    req.Turn,
+		Board:   &req.Board.BoardState,
+		Hazards: req.Board.Hazards,
+		You:     -1,
+	}
+	for i, s := range req.Board.Snakes {
+		if s.ID == req.You.ID {
+			g.You = i
+		}
+	}
+	if g.You == -1 {
+		return Game{}, fmt.Errorf("you not found: %s", req.You.ID)
+	}
+	if g.Timeout == 0 {
+		g.Timeout = defaultTimeout
+	}
+
+	return g, nil
+}
+
+// Request returns the Battlesnake API move request JSON of the game.
+func (g Game) Request() ([]byte, error) {
+	if g.You < 0 || g.You >= len(g.Board.Snakes) {
+		return nil, fmt.Errorf("no %c snake", you)
+	}
+
+	type coord struct {
+		X int32 `json:"x"`
+		Y int32 `json:"y"`
+	}
+	type snake struct {
+		ID      string      `json:"id"`
+		Name    string      `json:"name"`
+		Health  int32       `json:"health"`
+		Body    []coord     `json:"body"`
+		Head    coord       `json:"head"`
+		Length  int         `json:"length"`
+		Shout   string      `json:"shout"`
+		Latency interface{} `json:"latency"`
+	}
+	coords := func(points []rules.Point) []coord {
+		res := make([]coord, 0, len(points))
+		for _, p := range points {
+			res = append(res, coord{X: p.X, Y: p.Y})
+		}
+		return res
+	}
+
+	var req struct {
+		Game struct {
+			ID      string `json:"id"`
+			Ruleset *struct {
+				Name string `json:"name"`
+			} `json:"ruleset,omitempty"`
+			Timeout int32 `json:"timeout"`
+		} `json:"game"`
+		Turn  int `json:"turn"`
+		Board struct {
+			Height  int32   `json:"height"`
+			Width   int32   `json:"width"`
+			Food    []coord `json:"food"`
+			Hazards []coord `json:"hazards,omitempty"`
+			Snakes  []snake `json:"snakes"`
+		} `json:"board"`
+		You snake `json:"you"`
+	}
+	req.Game.ID = g.ID
+	if g.Ruleset != "" {
+		req.Game.Ruleset = &struct {
+			Name string `json:"name"`
+		}{Name: g.Ruleset}
+	}
+	req.Game.Timeout = g.Timeout
+	if req.Game.Timeout == 0 {
+		req.Game.Timeout = defaultTimeout
+	}
+	req.Turn = g.Turn
+	req.Board.Height = g.Board.Height
+	req.Board.Width = g.Board.Width
+	req.Board.Food = coords(g.Board.Food)
+	req.Board.Hazards = coords(g.Hazards)
+	for _, s := range g.Board.Snakes {
+		name, ok := g.Names[s.ID]
+		if !ok {
+			name = s.ID
+		}
+		req.Board.Snakes = append(req.Board.Snakes, snake{
+			ID:     s.ID,
+			Name:   name,
+			Health: s.Health,
+			Body:   coords(s.Body),
+			Head:   coord{X: s.Body[0].X, Y: s.Body[0].Y},
+			Length: len(s.Body),
+		})
+	}
+	req.You = req.Board.Snakes[g.You]
+
+	return json.MarshalIndent(req, "", " ")
+}
+
+// letter returns the head letter of the snake.
+func letter(g Game, idx int) rune {
+	if idx == g.You {
+		return you
+	}
+	return other
+}
+
+// offsets are the offsets of the body moves.
+var offsets = map[rune]rules.Point{
+	'.': {X: 0, Y: 0},
+	'u': {X: 0, Y: 1},
+	'd': {X: 0, Y: -1},
+	'r': {X: 1, Y: 0},
+	'l': {X: -1, Y: 0},
+}
+
+// bodyMoves returns the moves from the head to the tail of the body, "?" if not adjacent.
+func bodyMoves(body []rules.Point) string {
+	var res []rune
+	for i := 1; i < len(body); i++ {
+		d := rules.Point{X: body[i].X - body[i-1].X, Y: body[i].Y - body[i-1].Y}
+		m := '?'
+		for move, o := range offsets {
+			if o == d {
+				m = move
+			}
+		}
+		res = append(res, m)
+	}
+	return string(res)
+}
+
+// followBody returns the body of the moves from the head. Each body cell must be the
+// lowercase head letter, or the head itself if stacked on it.
+func followBody(cells map[rules.Point]rune, head rules.Point, bodyMoves string) ([]rules.Point, error) {
+	c := unicode.ToLower(cells[head])
+	body := []rules.Point{head}
+	for _, m := range bodyMoves {
+		d, ok := offsets[m]
+		if !ok {
+			return nil, fmt.Errorf("unknown body move: %q", m)
+		}
+
+		p := body[len(body)-1]
+		p = rules.Point{X: p.X + d.X, Y: p.Y + d.Y}
+		body = append(body, p)
+		if !unicode.IsLetter(cells[p]) {
+			return nil, fmt.Errorf("body at %d,%d not on grid", p.X, p.Y)
+		} else if cells[p] != c && p != head {
+			return nil, fmt.Errorf("body at %d,%d is another snake's", p.X, p.Y)
+		}
+	}
+	return body, nil
+}
+
+// traceBody returns the body from the head following the single unclaimed
+// neighbouring body cell of the letter.
+func traceBody(cells map[rules.Point]rune, claimed map[rules.Point]bool, head rules.Point) ([]rules.Point, error) {
+	c := unicode.ToLower(cells[head])
+	body := []rules.Point{head}
+	visited := map[rules.Point]bool{head: true}
+	for {
+		p := body[len(body)-1]
+		var next []rules.Point
+		for m, d := range offsets {
+			n := rules.Point{X: p.X + d.X, Y: p.Y + d.Y}
+			if m == '.' || visited[n] || claimed[n] || cells[n] != c {
+				continue
+			}
+			next = append(next, n)
+		}
+		if len(next) == 0 {
+			return body, nil
+		} else if len(next) > 1 {
+			return nil, fmt.Errorf("ambiguous body at %d,%d, add body moves", p.X, p.Y)
+		}
+		body = append(body, next[0])
+		visited[next[0]] = true
+	}
+}
+
+func parseSnakeLine(val string) (snakeLine, error) {
+	fields := strings.Fields(val)
+	if len(fields) == 0 || len([]rune(fields[0])) != 1 || !unicode.IsUpper([]rune(fields[0])[0]) {
+		return snakeLine{}, fmt.Errorf("snake: missing head letter")
+	}
+
+	l := snakeLine{Letter: []rune(fields[0])[0], Health: 100}
+	for _, f := range fields[1:] {
+		i := strings.Index(f, "=")
+		if i < 0 {
+			return snakeLine{}, fmt.Errorf("snake: invalid field: %s", f)
+		}
+		key, v := f[:i], f[i+1:]
+		switch key {
+		case "at":
+			p, err := parsePoint(v)
+			if err != nil {
+				return snakeLine{}, err
+			}
+			l.At = &p
+		case "id":
+			l.ID = v
+		case "health":
+			h, err := strconv.Atoi(v)
+			if err != nil {
+				return snakeLine{}, err
+			}
+			l.Health = int32(h)
+		case "body":
+			l.Body = &v
+		default:
+			return snakeLine{}, fmt.Errorf("snake: unknown field: %s", key)
+		}
+	}
+
+	return l, nil
+}
+
+func parsePoints(val string) ([]rules.Point, error) {
+	var res []rules.Point
+	for _, f := range strings.Fields(val) {
+		p, err := parsePoint(f)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+func parsePoint(s string) (rules.Point, error) {
+	i := strings.Index(s, ",")
+	if i < 0 {
+		return rules.Point{}, fmt.Errorf("invalid point: %s", s)
+	}
+	x, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return rules.Point{}, fmt.Errorf("invalid point: %s", s)
+	}
+	y, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return rules.Point{}, fmt.Errorf("invalid point: %s", s)
+	}
+	return rules.Point{X: int32(x), Y: int32(y)}, nil
+}
+
+// sortPoints sorts the points by x then y.
+func sortPoints(points []rules.Point) {
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].X != points[j].X {
+			return points[i].X < points[j].X
+		}
+		return points[i].Y < points[j].Y
+	})
+}
@@ -0,0 +1,188 @@
+package boardtxt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("../testdata/input-*.json")
+	jtest.RequireNil(t, err)
+	external, err := filepath.Glob("../testdata/external/*.board.json")
+	jtest.RequireNil(t, err)
+	files = append(files, external...)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			b, err := os.ReadFile(file)
+			jtest.RequireNil(t, err)
+
+			g, err := FromRequest(b)
+			jtest.RequireNil(t, err)
+			g = normalize(g)
+
+			text := Render(g)
+			res, err := Parse(text)
+			jtest.RequireNil(t, err)
+			require.Equal(t, g, res)
+			require.Equal(t, text, Render(res))
+
+			// The committed text board is rendered from the request.
+			txt, err := os.ReadFile(strings.TrimSuffix(file, ".json") + ".board.txt")
+			if os.IsNotExist(err) {
+				txt, err = os.ReadFile(strings.TrimSuffix(file, ".json") + ".txt")
+			}
+			jtest.RequireNil(t, err)
+			require.Equal(t, text, string(txt))
+
+			req, err := res.Request()
+			jtest.RequireNil(t, err)
+			res, err = FromRequest(req)
+			jtest.RequireNil(t, err)
+			res = normalize(res)
+			require.Equal(t, g, res)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	g, err := Parse(`
+turn: 7
+snake: S id=second health=50 body=uu.
+food: 2,2
+
+..*s
+.Yys
+...S
+Ss.S
+`)
+	jtest.RequireNil(t, err)
+
+	require.Equal(t, 7, g.Turn)
+	require.EqualValues(t, 4, g.Board.Width)
+	require.EqualValues(t, 4, g.Board.Height)
+	require.Equal(t, []rules.Point{{X: 2, Y: 2}, {X: 2, Y: 3}}, g.Board.Food)
+	require.Empty(t, g.Hazards)
+	require.Equal(t, []rules.Snake{
+		{ID: "second", Health: 50, Body: []rules.Point{{X: 3, Y: 1}, {X: 3, Y: 2}, {X: 3, Y: 3}, {X: 3, Y: 3}}},
+		{ID: "you", Health: 100, Body: []rules.Point{{X: 1, Y: 2}, {X: 2, Y: 2}}},
+		{ID: "snake-2", Health: 100, Body: []rules.Point{{X: 0, Y: 0}, {X: 1, Y: 0}}},
+	}, g.Board.Snakes[:3])
+	require.Equal(t, "snake-3", g.Board.Snakes[3].ID)
+	require.Len(t, g.Board.Snakes[3].Body, 1)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Board string
+		Err   string
+	}{
+		{
+			Name:  "empty",
+			Board: "turn: 1\n",
+			Err:   "empty grid",
+		},
+		{
+			Name:  "width",
+			Board: "...\n..\n",
+			Err:   "row 1: width 2 != 3",
+		},
+		{
+			Name:  "character",
+			Board: "..#\n",
+			Err:   "row 0: unknown character: '#'",
+		},
+		{
+			Name:  "header",
+			Board: "foo: bar\nY..\n",
+			Err:   "line 1: unknown header: foo",
+		},
+		{
+			Name:  "no head",
+			Board: "snake: S\nY..\n",
+			Err:   "snake S: no head",
+		},
+		{
+			Name:  "ambiguous",
+			Board: "yy.\nYy.\n",
+			Err:   "snake Y at 0,0: ambiguous body at 0,0, add body moves",
+		},
+		{
+			Name:  "body off grid",
+			Board: "snake: Y body=ul\n.y.\n.Y.\n",
+			Err:   "snake Y at 1,0: body at 0,1 not on grid",
+		},
+		{
+			Name:  "other body",
+			Board: "snake: Y body=r\nYs.\n",
+			Err:   "snake Y at 0,0: body at 1,0 is another snake's",
+		},
+		{
+			Name:  "orphan",
+			Board: "Y.s\n",
+			Err:   "body without snake at 2,0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := Parse(test.Board)
+			require.EqualError(t, err, test.Err)
+		})
+	}
+}
+
+// normalize sorts the food and hazards and drops empty slices as Parse does.
+func normalize(g Game) Game {
+	sortPoints(g.Board.Food)
+	sortPoints(g.Hazards)
+	if len(g.Board.Food) == 0 {
+		g.Board.Food = nil
+	}
+	if len(g.Hazards) == 0 {
+		g.Hazards = nil
+	}
+	return g
+}
+
+func TestRenderEmptyBody(t *testing.T) {
+	g := Game{
+		Board: &rules.BoardState{Width: 2, Height: 1, Snakes: []rules.Snake{
+			{ID: "you", Health: 90, Body: []rules.Point{{X: 0, Y: 0}}},
+			{ID: "gone", Health: 0},
+		}},
+	}
+	require.Equal(t, `turn: 0
+snake: Y at=0,0 id=you health=90 body=
+snake: S id=gone health=0 body=
+Y.
+`, Render(g))
+}
+
+func TestGameInfo(t *testing.T) {
+	text := "ruleset: royale\ntimeout: 300\nturn: 0\nsnake: Y at=0,0 id=you health=100 body=\nY.\n"
+	g, err := Parse(text)
+	jtest.RequireNil(t, err)
+	require.Equal(t, "royale", g.Ruleset)
+	require.EqualValues(t, 300, g.Timeout)
+	require.Equal(t, text, Render(g))
+
+	req, err := g.Request()
+	jtest.RequireNil(t, err)
+	res, err := FromRequest(req)
+	jtest.RequireNil(t, err)
+	require.Equal(t, g, normalize(res))
+
+	// The timeout defaults to 500ms.
+	g, err = Parse("Y.\n")
+	jtest.RequireNil(t, err)
+	require.EqualValues(t, 500, g.Timeout)
+	require.Empty(t, g.Ruleset)
+}
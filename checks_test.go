@@ -17,6 +17,8 @@ import (
 
 	"github.com/luno/jettison/jtest"
 	"github.com/stretchr/testify/require"
+
+	"github.com/corverroos/bsnake/boardtxt"
 )
 
 type input struct {
@@ -56,7 +58,7 @@ func TestFixInput(t *testing.T) {
 			req.Board.Snakes[i] = fixSnake(req.Board.Snakes[i])
 		}
 
-		viz := boardToViz(req)
+		viz := boardToViz(t, req)
 		err := os.WriteFile(strings.ReplaceAll(in.Path, ".json", ".board.txt"), []byte(viz), 0644)
 		jtest.RequireNil(t, err)
 
@@ -67,59 +69,51 @@ func TestFixInput(t *testing.T) {
 			jtest.RequireNil(t, err)
 		}
 	}
-}
 
-func boardToViz(req GameRequest) string {
-	var res [][]rune
-	const (
-		s  = '.'
-		sh = 'S'
-		sb = 's'
-		yh = 'Y'
-		yb = 'y'
-		f  = '*'
-		h  = '░'
-	)
-	for y := 0; y < req.Board.Height; y++ {
-		var row []rune
-		for x := 0; x < req.Board.Width; x++ {
-			row = append(row, s)
-		}
-		res = append(res, row)
-	}
-	for _, c := range req.Board.Hazards {
-		res[c.Y][c.X] = h
-	}
+	external, err := filepath.Glob("testdata/external/*.board.json")
+	jtest.RequireNil(t, err)
+	for _, file := range external {
+		b, err := os.ReadFile(file)
+		jtest.RequireNil(t, err)
 
-	for _, snake := range req.Board.Snakes {
-		h := sh
-		b := sb
-		if snake.ID == req.You.ID {
-			h = yh
-			b = yb
-		}
-		for i, c := range snake.Body {
-			r := b
-			if i == 0 {
-				r = h
-			}
-			res[c.Y][c.X] = r
-		}
-	}
-	for _, c := range req.Board.Food {
-		res[c.Y][c.X] = f
-	}
-	var sl []string
-	for _, row := range res {
-		sl = append([]string{string(row)}, sl...)
+		var req GameRequest
+		jtest.RequireNil(t, json.Unmarshal(b, &req))
+
+		viz := boardToViz(t, req)
+		err = os.WriteFile(strings.ReplaceAll(file, ".json", ".txt"), []byte(viz), 0644)
+		jtest.RequireNil(t, err)
 	}
-	return strings.Join(sl, "\n")
+}
+
+func boardToViz(t *testing.T, req GameRequest) string {
+	b, err := json.Marshal(req)
+	jtest.RequireNil(t, err)
+
+	g, err := boardtxt.FromRequest(b)
+	jtest.RequireNil(t, err)
+
+	return boardtxt.Render(g)
+}
+
+// parseBoard returns the request of the text board, see boardtxt.Parse.
+func parseBoard(t *testing.T, text string) GameRequest {
+	g, err := boardtxt.Parse(text)
+	jtest.RequireNil(t, err)
+
+	b, err := g.Request()
+	jtest.RequireNil(t, err)
+
+	var req GameRequest
+	jtest.RequireNil(t, json.Unmarshal(b, &req))
+
+	return req
 }
 
 func TestArea(t *testing.T) {
 	tests := []struct {
-		Name string
-		Exp  []string
+		Name  string
+		Board string // Text board, else the input file of the name.
+		Exp   []string
 	}{
 		{
 			Name: "001",
@@ -133,16 +127,31 @@ func TestArea(t *testing.T) {
 			Name: "003",
 			Exp:  []string{"up:0", "down:0", "right:8", "left:8"},
 		},
+		{
+			Name: "corner",
+			Board: `
+.....
+sss..
+..S.Y
+....y
+....y
+`,
+			Exp: []string{"up:13", "down:0", "right:0", "left:18"},
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
-			f, err := os.Open(path.Join("testdata", "input-"+test.Name+".json"))
-			jtest.RequireNil(t, err)
-
 			var req GameRequest
-			err = json.NewDecoder(f).Decode(&req)
-			jtest.RequireNil(t, err)
+			if test.Board != "" {
+				req = parseBoard(t, test.Board)
+			} else {
+				f, err := os.Open(path.Join("testdata", "input-"+test.Name+".json"))
+				jtest.RequireNil(t, err)
+
+				err = json.NewDecoder(f).Decode(&req)
+				jtest.RequireNil(t, err)
+			}
 
 			var areas []string
 			for _, m := range Moves {
@@ -150,7 +159,7 @@ func TestArea(t *testing.T) {
 				areas = append(areas, fmt.Sprintf("%s:%d", m.String(), a.Size()))
 			}
 
-			require.EqualValues(t, test.Exp, areas, boardToViz(req))
+			require.EqualValues(t, test.Exp, areas, boardToViz(t, req))
 		})
 	}
 }
@@ -160,8 +169,9 @@ func TestScoreMoves(t *testing.T) {
 	type Exp map[Move]func(t *testing.T, m Move, scores map[Move]int)
 
 	tests := []struct {
-		Name string
-		Exp  Exp
+		Name  string
+		Board string // Text board, else the input file of the name.
+		Exp   Exp
 	}{
 		{
 			Name: "001",
@@ -223,16 +233,31 @@ func TestScoreMoves(t *testing.T) {
 			Name: "015",
 			Exp:  Exp{Up: requireBody, Down: require1st, Left: requireBack, Right: require2nd},
 		},
+		{
+			Name: "pocket",
+			Board: `
+.....
+sssss
+....S
+yyy..
+..Y..
+`,
+			Exp: Exp{Up: requireBack, Down: requireWall, Left: requireBad, Right: require1st},
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
-			f, err := os.Open(path.Join("testdata", "input-"+test.Name+".json"))
-			jtest.RequireNil(t, err)
-
 			var req GameRequest
-			err = json.NewDecoder(f).Decode(&req)
-			jtest.RequireNil(t, err)
+			if test.Board != "" {
+				req = parseBoard(t, test.Board)
+			} else {
+				f, err := os.Open(path.Join("testdata", "input-"+test.Name+".json"))
+				jtest.RequireNil(t, err)
+
+				err = json.NewDecoder(f).Decode(&req)
+				jtest.RequireNil(t, err)
+			}
 
 			w := basicWeights
 
@@ -360,7 +385,7 @@ func reloadExternal(t *testing.T) {
 		err = os.WriteFile(fmt.Sprintf("testdata/external/%02d.board.json", i), js, 0644)
 		jtest.RequireNil(t, err)
 
-		viz := boardToViz(req)
+		viz := boardToViz(t, req)
 		err = os.WriteFile(fmt.Sprintf("testdata/external/%02d.board.txt", i), []byte(viz), 0644)
 		jtest.RequireNil(t, err)
 
@@ -0,0 +1,191 @@
+// Package export imports locally saved Battlesnake engine game exports, the per-turn
+// frames of a game, as board positions from the perspective of any snake.
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/BattlesnakeOfficial/rules"
+
+	"github.com/corverroos/bsnake/boardtxt"
+)
+
+// Export is a game of frames by turn.
+type Export struct {
+	ID      string
+	Width   int32  // Board width, zero if the export lacks the game info.
+	Height  int32  // Board height, zero if the export lacks the game info.
+	Ruleset string // Ruleset name, e.g. "royale", empty if the export lacks the game info.
+	Timeout int32  // Move timeout in milliseconds, zero if the export lacks the game info.
+	Frames  []Frame
+}
+
+// Frame is the state of the board at a turn.
+type Frame struct {
+	Turn    int
+	Snakes  []Snake
+	Food    []rules.Point
+	Hazards []rules.Point
+}
+
+// Snake is a snake of a frame, including eliminated snakes.
+type Snake struct {
+	ID     string
+	Name   string
+	Body   []rules.Point
+	Health int32
+	Death  *struct{ Cause string } // Elimination, nil if alive.
+}
+
+// game is the engine's game info.
+type game struct {
+	ID      string
+	Width   int32
+	Height  int32
+	Ruleset struct {
+		Name string
+	}
+	SnakeTimeout int32
+}
+
+// object is any of the supported top-level JSON objects.
+type object struct {
+	// Websocket event of a frame or the game info at the end of the game.
+	Type string
+	Data json.RawMessage
+
+	// Game info and frames response.
+	Game   *game
+	Frames []Frame
+
+	// Frame
+	Turn    int
+	Snakes  []Snake
+	Food    []rules.Point
+	Hazards []rules.Point
+}
+
+// Parse returns the export of the engine's JSON game data. Supported are the frames response,
+// optionally with the game info as "Game", a JSON array of frames and the websocket stream of
+// frame and game_end events or frames, one JSON object per line.
+func Parse(b []byte) (Export, error) {
+	var e Export
+	frames := make(map[int]Frame)
+
+	b = bytes.TrimSpace(b)
+	if bytes.HasPrefix(b, []byte("[")) {
+		var fl []Frame
+		if err := json.Unmarshal(b, &fl); err != nil {
+			return Export{}, err
+		}
+		for _, f := range fl {
+			frames[f.Turn] = f
+		}
+	} else {
+		d := json.NewDecoder(bytes.NewReader(b))
+		for {
+			var o object
+			if err := d.Decode(&o); err == io.EOF {
+				break
+			} else if err != nil {
+				return Export{}, err
+			}
+
+			switch {
+			case o.Type == "frame":
+				var f Frame
+				if err := json.Unmarshal(o.Data, &f); err != nil {
+					return Export{}, err
+				}
+				frames[f.Turn] = f
+			case o.Type == "game_end":
+				o.Game = new(game)
+				if err := json.Unmarshal(o.Data, o.Game); err != nil {
+					return Export{}, err
+				}
+			case o.Type != "":
+				return Export{}, fmt.Errorf("unknown event type: %s", o.Type)
+			case o.Snakes != nil:
+				frames[o.Turn] = Frame{Turn: o.Turn, Snakes: o.Snakes, Food: o.Food, Hazards: o.Hazards}
+			}
+
+			for _, f := range o.Frames {
+				frames[f.Turn] = f
+			}
+			if o.Game != nil {
+				e.ID, e.Width, e.Height = o.Game.ID, o.Game.Width, o.Game.Height
+				e.Ruleset, e.Timeout = o.Game.Ruleset.Name, o.Game.SnakeTimeout
+			}
+		}
+	}
+
+	if len(frames) == 0 {
+		return Export{}, fmt.Errorf("no frames")
+	}
+
+	for _, f := range frames {
+		e.Frames = append(e.Frames, f)
+	}
+	sort.Slice(e.Frames, func(i, j int) bool {
+		return e.Frames[i].Turn < e.Frames[j].Turn
+	})
+
+	return e, nil
+}
+
+// Game returns the board position at the turn, the last if -1, from the perspective of the
+// snake by ID or name. Eliminated snakes are excluded.
+func (e Export) Game(turn int, snake string) (boardtxt.Game, error) {
+	if e.Width == 0 || e.Height == 0 {
+		return boardtxt.Game{}, fmt.Errorf("unknown board size")
+	}
+
+	f := e.Frames[len(e.Frames)-1]
+	if turn != -1 {
+		var found bool
+		for _, frame := range e.Frames {
+			if frame.Turn == turn {
+				f, found = frame, true
+			}
+		}
+		if !found {
+			return boardtxt.Game{}, fmt.Errorf("turn %d not in turns %d-%d",
+				turn, e.Frames[0].Turn, e.Frames[len(e.Frames)-1].Turn)
+		}
+	}
+
+	g := boardtxt.Game{
+		ID:      e.ID,
+		Ruleset: e.Ruleset,
+		Timeout: e.Timeout,
+		Turn:    f.Turn,
+		Board:   &rules.BoardState{Width: e.Width, Height: e.Height, Food: f.Food},
+		Hazards: f.Hazards,
+		You:     -1,
+		Names:   make(map[string]string),
+	}
+
+	var alive []string
+	for _, s := range f.Snakes {
+		if s.Death != nil {
+			continue
+		}
+		if s.ID == snake || s.Name == snake {
+			g.You = len(g.Board.Snakes)
+		}
+		g.Board.Snakes = append(g.Board.Snakes, rules.Snake{ID: s.ID, Body: s.Body, Health: s.Health})
+		g.Names[s.ID] = s.Name
+		alive = append(alive, fmt.Sprintf("%s (%s)", s.ID, s.Name))
+	}
+	if g.You == -1 {
+		return boardtxt.Game{}, fmt.Errorf("snake %q not alive at turn %d, alive: %s",
+			snake, f.Turn, strings.Join(alive, ", "))
+	}
+
+	return g, nil
+}
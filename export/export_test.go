@@ -0,0 +1,114 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/corverroos/bsnake/boardtxt"
+)
+
+func TestGame(t *testing.T) {
+	b, err := os.ReadFile("../testdata/export/frames.json")
+	jtest.RequireNil(t, err)
+
+	e, err := Parse(b)
+	jtest.RequireNil(t, err)
+	require.Equal(t, "e3a9c1f0-4b2d-4f7e-9c55-0b1d2e3f4a5b", e.ID)
+	require.EqualValues(t, 7, e.Width)
+	require.EqualValues(t, 7, e.Height)
+	require.Equal(t, "royale", e.Ruleset)
+	require.EqualValues(t, 500, e.Timeout)
+	require.Len(t, e.Frames, 3)
+
+	g, err := e.Game(1, "beta")
+	jtest.RequireNil(t, err)
+	require.Equal(t, 1, g.You)
+	require.Equal(t, map[string]string{"gs_a": "alpha", "gs_b": "beta"}, g.Names)
+	require.Equal(t, `game: e3a9c1f0-4b2d-4f7e-9c55-0b1d2e3f4a5b
+ruleset: royale
+turn: 1
+snake: S at=1,2 id=gs_a health=99 body=d.
+snake: Y at=5,6 id=gs_b health=99 body=d.
+.....Y.
+.....y.
+.......
+...*...
+*S.....
+.s.....
+......░
+`, boardtxt.Render(g))
+
+	b, err = g.Request()
+	jtest.RequireNil(t, err)
+	var req struct {
+		Game struct {
+			Ruleset struct {
+				Name string
+			}
+			Timeout int32
+		}
+		You struct {
+			ID   string
+			Name string
+		}
+	}
+	jtest.RequireNil(t, json.Unmarshal(b, &req))
+	require.Equal(t, "royale", req.Game.Ruleset.Name)
+	require.EqualValues(t, 500, req.Game.Timeout)
+	require.Equal(t, "gs_b", req.You.ID)
+	require.Equal(t, "beta", req.You.Name)
+
+	// Eliminated snakes are excluded.
+	g, err = e.Game(-1, "gs_a")
+	jtest.RequireNil(t, err)
+	require.Equal(t, 2, g.Turn)
+	require.Equal(t, []rules.Snake{{
+		ID:     "gs_a",
+		Health: 100,
+		Body:   []rules.Point{{X: 0, Y: 2}, {X: 1, Y: 2}, {X: 1, Y: 1}, {X: 1, Y: 1}},
+	}}, g.Board.Snakes)
+
+	_, err = e.Game(2, "beta")
+	require.EqualError(t, err, `snake "beta" not alive at turn 2, alive: gs_a (alpha)`)
+
+	_, err = e.Game(3, "beta")
+	require.EqualError(t, err, "turn 3 not in turns 0-2")
+}
+
+func TestParseStream(t *testing.T) {
+	b, err := os.ReadFile("../testdata/export/frames.json")
+	jtest.RequireNil(t, err)
+
+	exp, err := Parse(b)
+	jtest.RequireNil(t, err)
+
+	// Websocket events, one per line and out of order.
+	var lines []string
+	for _, i := range []int{1, 0, 2} {
+		data, err := json.Marshal(exp.Frames[i])
+		jtest.RequireNil(t, err)
+		lines = append(lines, `{"Type":"frame","Data":`+string(data)+`}`)
+	}
+	lines = append(lines, `{"Type":"game_end","Data":{"ID":"e3a9c1f0-4b2d-4f7e-9c55-0b1d2e3f4a5b","Width":7,"Height":7,"Ruleset":{"name":"royale"},"SnakeTimeout":500}}`)
+
+	e, err := Parse([]byte(strings.Join(lines, "\n")))
+	jtest.RequireNil(t, err)
+	require.Equal(t, exp, e)
+
+	// An array of frames lacks the board size.
+	frames, err := json.Marshal(exp.Frames)
+	jtest.RequireNil(t, err)
+
+	e, err = Parse(frames)
+	jtest.RequireNil(t, err)
+	require.Equal(t, exp.Frames, e.Frames)
+
+	_, err = e.Game(0, "alpha")
+	require.EqualError(t, err, "unknown board size")
+}
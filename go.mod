@@ -4,7 +4,6 @@ go 1.16
 
 require (
 	github.com/BattlesnakeOfficial/rules v1.0.17
-	github.com/corverroos/bsrules v1.0.18-0.20210625064428-db82f67fd124 // indirect
 	github.com/julienschmidt/httprouter v1.3.0
 	github.com/luno/jettison v0.0.0-20210526084548-7f4f94692e7a
 	github.com/stretchr/testify v1.4.0
@@ -9,11 +9,31 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
 
+// HandleSnakes responds with the names of the served snakes excluding aliases, see arena.HTTPEngine.
+func HandleSnakes(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	var names []string
+	for name, s := range snakes {
+		if name != s.Alias {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(names)
+	if err != nil {
+		fmt.Println("ERROR: response write: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+}
+
 // HandleIndex is called when your Battlesnake is created and refreshed
 // by play.battlesnake.com. BattlesnakeInfoResponse contains information about
 // your Battlesnake, including what it should look like on the game board.
@@ -70,8 +90,6 @@ func HandleStart(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 // TODO: Use the information in the GameRequest object to determine your next move.
 func HandleMove(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	t0 := time.Now()
-	ctx, cancel := context.WithTimeout(r.Context(), time.Millisecond*500)
-	defer cancel()
 	name := p.ByName("name")
 
 	req := GameRequest{}
@@ -82,11 +100,20 @@ func HandleMove(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		return
 	}
 
+	deadline := t0.Add(time.Millisecond * 500)
+	if req.Game.Timeout > 0 {
+		deadline = t0.Add(time.Millisecond * time.Duration(req.Game.Timeout))
+	}
+	ctx, cancel := context.WithDeadline(r.Context(), deadline)
+	defer cancel()
+
 	fn := snakes[name].Move
 	if fn == nil {
 		return
 	}
 
+	observeTimeouts(req)
+
 	m, err := fn(ctx, req)
 	if err != nil {
 		log.Printf("ERROR: handleMove: %v\n", err)
@@ -107,7 +134,7 @@ func HandleMove(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	}
 
 	var timeout string
-	if fmt.Sprint(req.You.Latency) == "0" {
+	if timedOut(req.You) {
 		timeout = " TIMEOUT!"
 	}
 	log.Printf("Move %s: %d %v [%vus %sms%s]\n", name, req.Turn, m, time.Since(t0).Microseconds(), req.You.Latency, timeout)
@@ -127,10 +154,11 @@ func HandleEnd(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	}
 
 	var timeout string
-	if req.You.Latency == "0" {
+	if timedOut(req.You) {
 		timeout = " TIMEOUT!"
 	}
 	log.Printf("End %s: %d [%sms%s]\n", name, req.Turn, req.You.Latency, timeout)
+	endTimeouts(req)
 
 	fn := snakes[name].End
 	if fn == nil {
@@ -147,6 +175,8 @@ func HandleEnd(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 
 func main() {
 	rand.Seed(time.Now().UnixNano())
+	loadOpponents()
+	loadTuned()
 
 	bind := os.Getenv("BIND")
 	if len(bind) == 0 {
@@ -154,6 +184,7 @@ func main() {
 	}
 
 	router := httprouter.New()
+	router.GET("/", HandleSnakes)
 	router.GET("/:name/", HandleIndex)
 	router.POST("/:name/start", HandleStart)
 	router.POST("/:name/move", HandleMove)
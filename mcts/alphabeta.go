@@ -0,0 +1,264 @@
+package mcts
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/BattlesnakeOfficial/rules"
+
+	"github.com/corverroos/bsnake/board"
+	"github.com/corverroos/bsnake/heur"
+)
+
+const maxABDepth = 64
+
+var errABTimeout = errors.New("alpha-beta timeout")
+
+// ABResult is the result of an iterative deepening alpha-beta search.
+type ABResult struct {
+	Move  string
+	Score float64
+	Depth int // Depth of the last completed iteration
+	Nodes int
+}
+
+// AlphaBeta does an iterative deepening paranoid alpha-beta search from the
+// root snake's perspective until the context is done or maxDepth is reached.
+// Simultaneous moves are modelled by the root snake moving first with
+// all opponents minimising the root score as a coalition. With two snakes this is
+// the conventional conservative simultaneous-move reduction.
+func AlphaBeta(ctx context.Context, root *node, o *Opts, hazards map[rules.Point]bool, maxDepth int) (ABResult, error) {
+	s := &abSearch{
+		ctx:     ctx,
+		o:       o,
+		hazards: hazards,
+		rootIdx: root.rootIdx,
+	}
+
+	var res ABResult
+	for depth := 1; depth <= maxDepth; depth++ {
+		s.cutoff = false
+
+		scores, err := s.searchRoot(root, depth)
+		if errors.Is(err, errABTimeout) {
+			break
+		} else if err != nil {
+			return ABResult{}, err
+		}
+
+		s.prevScores = scores
+		res.Depth = depth
+		res.Move, res.Score = bestScore(scores)
+
+		if !s.cutoff {
+			// Whole tree searched, deeper iterations won't change anything.
+			break
+		}
+	}
+
+	res.Nodes = s.nodes
+
+	return res, nil
+}
+
+type abSearch struct {
+	ctx        context.Context
+	o          *Opts
+	hazards    map[rules.Point]bool
+	rootIdx    int
+	prevScores map[string]float64 // Root move scores of the previous iteration, used for ordering.
+	cutoff     bool               // True if any non-terminal node was evaluated heuristically.
+	nodes      int
+}
+
+// abGroup is all the joint move children of a node with the same root snake move.
+type abGroup struct {
+	move   string
+	childs []abChild
+	order  float64
+}
+
+type abChild struct {
+	n     *node
+	term  bool
+	score float64 // Terminal or heuristic score
+}
+
+// searchRoot returns the score of each root move searched to the depth.
+func (s *abSearch) searchRoot(root *node, depth int) (map[string]float64, error) {
+	groups, err := s.expand(root, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.prevScores != nil {
+		// Order by previous iteration scores, best first.
+		for i := range groups {
+			groups[i].order = s.prevScores[groups[i].move]
+		}
+		sort.SliceStable(groups, func(i, j int) bool {
+			return groups[i].order > groups[j].order
+		})
+	}
+
+	res := make(map[string]float64)
+	alpha := math.Inf(-1)
+	for _, g := range groups {
+		v, err := s.min(g, depth, alpha, math.Inf(1))
+		if err != nil {
+			return nil, err
+		}
+		res[g.move] = v
+		if v > alpha {
+			alpha = v
+		}
+	}
+
+	return res, nil
+}
+
+// max returns the best score for the root snake at node n.
+func (s *abSearch) max(n *node, depth int, alpha, beta float64) (float64, error) {
+	groups, err := s.expand(n, depth)
+	if err != nil {
+		return 0, err
+	}
+
+	best := math.Inf(-1)
+	for _, g := range groups {
+		v, err := s.min(g, depth, alpha, beta)
+		if err != nil {
+			return 0, err
+		}
+		if v > best {
+			best = v
+		}
+		if v > alpha {
+			alpha = v
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+
+	return best, nil
+}
+
+// min returns the worst score for the root snake for the opponent replies in the group.
+func (s *abSearch) min(g abGroup, depth int, alpha, beta float64) (float64, error) {
+	best := math.Inf(1)
+	for _, c := range g.childs {
+		v := c.score
+		if !c.term && depth > 1 {
+			var err error
+			v, err = s.max(c.n, depth-1, alpha, beta)
+			if err != nil {
+				return 0, err
+			}
+		} else if !c.term {
+			s.cutoff = true
+		}
+
+		if v < best {
+			best = v
+		}
+		if v < beta {
+			beta = v
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+
+	return best, nil
+}
+
+// expand returns the children of n grouped by root snake move. Groups and childs are
+// ordered by heuristic scores if they are not leafs.
+func (s *abSearch) expand(n *node, depth int) ([]abGroup, error) {
+	if err := s.checkTimeout(); err != nil {
+		return nil, err
+	}
+
+	var groups []abGroup
+	for _, moves := range genMoveSet(n, s.o) {
+		tup, err := genChild(n, moves)
+		if err != nil {
+			return nil, err
+		}
+		s.nodes++
+
+		c := abChild{n: tup.child}
+		if totals, ok, err := c.n.CheckTerminal(s.o); err != nil {
+			return nil, err
+		} else if ok {
+			c.term = true
+			c.score = totals[s.rootIdx]
+		} else {
+			c.score = heur.Calc(s.o.HeurFactors, c.n.board, s.rootIdx, s.hazards)[s.rootIdx]
+		}
+
+		move := moves[s.rootIdx]
+
+		var found bool
+		for i := range groups {
+			if groups[i].move == move {
+				groups[i].childs = append(groups[i].childs, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			groups = append(groups, abGroup{move: move, childs: []abChild{c}})
+		}
+	}
+
+	if depth == 1 {
+		return groups, nil
+	}
+
+	for i := range groups {
+		g := &groups[i]
+		sort.SliceStable(g.childs, func(i, j int) bool {
+			return g.childs[i].score < g.childs[j].score
+		})
+		g.order = g.childs[0].score
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].order > groups[j].order
+	})
+
+	return groups, nil
+}
+
+func (s *abSearch) checkTimeout() error {
+	if s.prevScores == nil {
+		// Always complete the first iteration.
+		return nil
+	}
+	if s.ctx.Err() != nil {
+		return errABTimeout
+	}
+	return nil
+}
+
+func bestScore(scores map[string]float64) (string, float64) {
+	var (
+		res string
+		max = math.Inf(-1)
+	)
+	for _, move := range board.Moves {
+		score, ok := scores[move]
+		if !ok {
+			continue
+		}
+		if res == "" || score > max {
+			res = move
+			max = score
+		}
+	}
+	return res, max
+}
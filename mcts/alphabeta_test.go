@@ -0,0 +1,86 @@
+package mcts
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/corverroos/bsnake/heur"
+)
+
+var abFactors = heur.Factors{
+	Control: 0.1,
+	Length:  0.3,
+	Hunger:  -0.02,
+	Starve:  -0.8,
+}
+
+func TestAlphaBetaMatchesMinimax(t *testing.T) {
+	files := []string{
+		"../testdata/input-017.json",
+		"../testdata/input-020.json",
+		"../testdata/input-022.json",
+		"../testdata/input-027.json",
+		"../testdata/input-028.json",
+	}
+
+	for _, file := range files {
+		for depth := 1; depth <= 3; depth++ {
+			t.Run(fmt.Sprintf("%s_%d", file, depth), func(t *testing.T) {
+				b, rootIdx := fileToBoard(t, file)
+				o := &Opts{HeurFactors: &abFactors}
+
+				root := NewRoot(&rules.StandardRuleset{}, b, rootIdx)
+				mxl, err := Minimax(root, o, nil, depth)
+				jtest.RequireNil(t, err)
+
+				root = NewRoot(&rules.StandardRuleset{}, b, rootIdx)
+				res, err := AlphaBeta(context.Background(), root, o, nil, depth)
+				jtest.RequireNil(t, err)
+
+				require.Equal(t, mxl[rootIdx].minimax, res.Score)
+				require.True(t, res.Depth <= depth, res.Depth)
+			})
+		}
+	}
+}
+
+func TestAlphaBetaDeadline(t *testing.T) {
+	b, rootIdx := fileToBoard(t, "../testdata/input-020.json")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	root := NewRoot(&rules.StandardRuleset{}, b, rootIdx)
+	res, err := AlphaBeta(ctx, root, &Opts{HeurFactors: &abFactors}, nil, maxABDepth)
+	jtest.RequireNil(t, err)
+
+	// The search was stopped by the deadline, not by exhausting the depth.
+	require.Equal(t, context.DeadlineExceeded, ctx.Err())
+	require.True(t, res.Depth > 2, res.Depth)
+	require.True(t, res.Depth < maxABDepth, res.Depth)
+	require.Equal(t, "down", res.Move)
+}
+
+func TestSelectMinimax(t *testing.T) {
+	b, rootIdx := fileToBoard(t, "../testdata/input-020.json")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*150)
+	defer cancel()
+
+	var depth int
+	o := &Opts{HeurFactors: &abFactors}
+	o.logd = func(msg string, args ...interface{}) {
+		depth = args[0].(int)
+	}
+
+	move, err := SelectMinimax(ctx, b, nil, rootIdx, o)
+	jtest.RequireNil(t, err)
+	require.Equal(t, "down", move)
+	require.True(t, depth > 2, depth)
+}
@@ -0,0 +1,206 @@
+package mcts
+
+import (
+	"math"
+
+	"github.com/corverroos/bsnake/board"
+)
+
+const (
+	// BanditExp3 samples moves from the Exp3 mixed strategy.
+	// See https://mlanctot.info/files/papers/cig14-smmcts.pdf
+	BanditExp3 Bandit = 2
+
+	// BanditRM samples moves from the regret matching mixed strategy.
+	// See https://mlanctot.info/files/papers/cig14-smmcts.pdf
+	BanditRM Bandit = 3
+)
+
+// isMixed returns true if the bandit samples from a mixed strategy.
+func (b Bandit) isMixed() bool {
+	return b == BanditExp3 || b == BanditRM
+}
+
+// banditStats are the per snake Exp3 or regret matching statistics of a node.
+type banditStats struct {
+	bandit Bandit
+	gains  []float64 // Exp3 importance weighted gains or regret matching regrets by move.
+	strat  []float64 // Cumulative mixed strategy by move, see MixedStrategy.
+	probs  []float64 // Mixed strategy of the pending selection.
+	move   int       // Pending selected move index or -1.
+}
+
+// sampleBandit returns the move index sampled from the mixed strategy of the snake
+// over the available move indexes. The selection is pending until the next propagation.
+func (n *node) sampleBandit(o *Opts, snakeIdx int, avail []int) int {
+	if n.bandits == nil {
+		n.bandits = make([]banditStats, len(n.idsByIdx))
+	}
+
+	bs := &n.bandits[snakeIdx]
+	if bs.gains == nil {
+		bs.bandit = o.Bandit
+		bs.gains = make([]float64, len(board.Moves))
+		bs.strat = make([]float64, len(board.Moves))
+		bs.probs = make([]float64, len(board.Moves))
+	}
+
+	gamma := o.BanditGamma
+	if gamma == 0 {
+		gamma = 0.1
+	}
+	k := float64(len(avail))
+
+	for i := range bs.probs {
+		bs.probs[i] = 0
+	}
+
+	switch o.Bandit {
+	case BanditExp3:
+		max := math.Inf(-1)
+		for _, midx := range avail {
+			max = math.Max(max, bs.gains[midx])
+		}
+
+		var sum float64
+		for _, midx := range avail {
+			bs.probs[midx] = math.Exp((gamma / k) * (bs.gains[midx] - max))
+			sum += bs.probs[midx]
+		}
+		for _, midx := range avail {
+			bs.probs[midx] = (1-gamma)*bs.probs[midx]/sum + gamma/k
+		}
+	case BanditRM:
+		var sum float64
+		for _, midx := range avail {
+			sum += math.Max(0, bs.gains[midx])
+		}
+		for _, midx := range avail {
+			if sum > 0 {
+				bs.probs[midx] = (1-gamma)*math.Max(0, bs.gains[midx])/sum + gamma/k
+			} else {
+				bs.probs[midx] = 1 / k
+			}
+		}
+	default:
+		panic("not a mixed strategy bandit")
+	}
+
+	r := o.float64()
+	bs.move = avail[len(avail)-1]
+	for _, midx := range avail {
+		r -= bs.probs[midx]
+		if r < 0 {
+			bs.move = midx
+			break
+		}
+	}
+
+	for _, midx := range avail {
+		bs.strat[midx] += bs.probs[midx]
+	}
+
+	return bs.move
+}
+
+// updateBandits updates the pending bandit selections of n with the propagated totals.
+func (n *node) updateBandits(totals []float64) {
+	for i := range n.bandits {
+		bs := &n.bandits[i]
+		if bs.gains == nil || bs.move < 0 {
+			continue
+		}
+
+		// Map rewards from [-1,1] to [0,1]
+		reward := math.Max(0, math.Min(1, (totals[i]+1)/2))
+
+		switch bs.bandit {
+		case BanditExp3:
+			bs.gains[bs.move] += reward / bs.probs[bs.move]
+		case BanditRM:
+			for midx, p := range bs.probs {
+				if p == 0 {
+					continue
+				}
+				var est float64
+				if midx == bs.move {
+					est = reward / p
+				}
+				bs.gains[midx] += est - reward
+			}
+		}
+
+		bs.move = -1
+	}
+}
+
+// MixedStrategy returns the probability of each move of the snake. It is the
+// average mixed strategy for Exp3 and regret matching bandits and the visit
+// distribution otherwise.
+func (n *node) MixedStrategy(snakeIdx int) map[string]float64 {
+	res := make(map[string]float64)
+
+	if n.bandits != nil && n.bandits[snakeIdx].strat != nil {
+		var sum float64
+		for _, s := range n.bandits[snakeIdx].strat {
+			sum += s
+		}
+		for midx, s := range n.bandits[snakeIdx].strat {
+			if s > 0 {
+				res[board.Moves[midx]] = s / sum
+			}
+		}
+		return res
+	}
+
+	var sum float64
+	for _, tuple := range n.childs {
+		for _, move := range board.Moves {
+			if tuple.edge.Is(snakeIdx, move) {
+				res[move] += tuple.child.n
+				sum += tuple.child.n
+			}
+		}
+	}
+	for move := range res {
+		res[move] /= sum
+	}
+
+	return res
+}
+
+// SampleMove returns a move of the snake sampled from its mixed strategy.
+func (n *node) SampleMove(o *Opts, snakeIdx int) string {
+	strat := n.MixedStrategy(snakeIdx)
+
+	var last string
+	r := o.float64()
+	for _, move := range board.Moves {
+		p, ok := strat[move]
+		if !ok {
+			continue
+		}
+		last = move
+		r -= p
+		if r < 0 {
+			return move
+		}
+	}
+
+	return last
+}
+
+func (b Bandit) String() string {
+	switch b {
+	case BanditUCB1:
+		return "ucb1"
+	case BanditPUCT:
+		return "puct"
+	case BanditExp3:
+		return "exp3"
+	case BanditRM:
+		return "rm"
+	default:
+		return "unknown"
+	}
+}
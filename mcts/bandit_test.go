@@ -0,0 +1,263 @@
+package mcts
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBanditBestArm(t *testing.T) {
+	for _, bandit := range []Bandit{BanditExp3, BanditRM} {
+		t.Run(bandit.String(), func(t *testing.T) {
+			rand.Seed(0)
+			o := &Opts{Bandit: bandit}
+			n := &node{idsByIdx: []string{"a"}}
+
+			rewards := []float64{-0.5, 0.5, 0, -1} // up, down, right, left
+			for i := 0; i < 5000; i++ {
+				midx := n.sampleBandit(o, 0, []int{0, 1, 2, 3})
+				n.updateBandits([]float64{rewards[midx]})
+			}
+
+			strat := n.MixedStrategy(0)
+			fmt.Printf("strat=%v\n", strat)
+			for move, p := range strat {
+				if move != "down" {
+					require.True(t, p < strat["down"], move)
+				}
+			}
+		})
+	}
+}
+
+func TestBanditMatchingPennies(t *testing.T) {
+	// Snake 0 wins if both snakes select the same move, snake 1 wins otherwise.
+	// The Nash equilibrium is to select each move with equal probability.
+	for _, bandit := range []Bandit{BanditExp3, BanditRM} {
+		t.Run(bandit.String(), func(t *testing.T) {
+			rand.Seed(0)
+			o := &Opts{Bandit: bandit}
+			n := &node{idsByIdx: []string{"a", "b"}}
+
+			for i := 0; i < 20000; i++ {
+				m0 := n.sampleBandit(o, 0, []int{0, 1})
+				m1 := n.sampleBandit(o, 1, []int{0, 1})
+				r := 1.0
+				if m0 != m1 {
+					r = -1
+				}
+				n.updateBandits([]float64{r, -r})
+			}
+
+			for i := 0; i < 2; i++ {
+				strat := n.MixedStrategy(i)
+				fmt.Printf("snake=%d strat=%v\n", i, strat)
+				require.InDelta(t, 0.5, strat["up"], 0.1)
+				require.InDelta(t, 0.5, strat["down"], 0.1)
+			}
+		})
+	}
+}
+
+func TestBanditOnce(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Exp   string
+		ExpRM string // Regret matching differs, it converges slower on close moves.
+	}{
+		{
+			Name:  "../testdata/input-001.json",
+			Exp:   "down",
+			ExpRM: "right",
+		},
+		{
+			Name: "../testdata/input-017.json",
+			Exp:  "left",
+		},
+		{
+			Name:  "../testdata/input-020.json",
+			Exp:   "down",
+			ExpRM: "up",
+		},
+		{
+			Name: "../testdata/input-022.json",
+			Exp:  "right",
+		},
+		{
+			Name: "../testdata/input-023.json",
+			Exp:  "right",
+		},
+		{
+			Name: "../testdata/input-024.json",
+			Exp:  "left",
+		},
+		{
+			Name: "../testdata/input-025.json",
+			Exp:  "right",
+		},
+		{
+			Name: "../testdata/input-027.json",
+			Exp:  "left",
+		},
+		{
+			Name: "../testdata/input-028.json",
+			Exp:  "up",
+		},
+		{
+			Name: "../testdata/input-030.json",
+			Exp:  "up",
+		},
+		{
+			Name:  "../testdata/input-031.json",
+			Exp:   "right",
+			ExpRM: "left",
+		},
+	}
+
+	for _, test := range tests {
+		for _, bandit := range []Bandit{BanditExp3, BanditRM} {
+			t.Run(test.Name+"_"+bandit.String(), func(t *testing.T) {
+				b, rootIdx := fileToBoard(t, test.Name)
+
+				o := OptsV5
+				o.Bandit = bandit
+
+				var r rules.Ruleset = &rules.StandardRuleset{}
+				if len(b.Snakes) == 1 {
+					r = &rules.SoloRuleset{}
+				}
+
+				root := NewRoot(r, b, rootIdx)
+				for i := 0; i < 5000; i++ {
+					rand.Seed(int64(i))
+					jtest.RequireNil(t, Once(root, &o))
+				}
+
+				strat := root.MixedStrategy(rootIdx)
+				fmt.Printf("strat=%v robust=%v\n", strat, root.RobustMoves(rootIdx))
+
+				var max string
+				for move, p := range strat {
+					if max == "" || p > strat[max] {
+						max = move
+					}
+				}
+
+				exp := test.Exp
+				if bandit == BanditRM && test.ExpRM != "" {
+					exp = test.ExpRM
+				}
+				require.Equal(t, exp, max)
+			})
+		}
+	}
+}
+
+var arena = flag.Bool("arena", false, "play arena games")
+
+// TestBanditArena plays games between decoupled UCT and the mixed strategy bandits
+// and requires decoupled UCT to win more games than each.
+// Run with: go test ./mcts -run TestBanditArena -arena
+//
+// OptsV5, 1000 iterations per move, 20 games:
+//
+//	duct vs exp3: map[draw:1 duct:19]
+//	duct vs rm: map[draw:1 duct:19]
+//	duct vs exp3 (SampleFinal=false): map[draw:6 duct:14]
+//	duct vs rm (SampleFinal=false): map[draw:4 duct:16]
+func TestBanditArena(t *testing.T) {
+	if !*arena {
+		t.Skip("arena disabled")
+	}
+
+	duct := OptsV5
+	exp3 := OptsV5
+	exp3.Bandit = BanditExp3
+	exp3.SampleFinal = true
+	rm := OptsV5
+	rm.Bandit = BanditRM
+	rm.SampleFinal = true
+
+	for name, o := range map[string]Opts{"exp3": exp3, "rm": rm} {
+		o := o
+		wins := make(map[string]int)
+		for i := 0; i < 20; i++ {
+			rand.Seed(int64(i))
+
+			// Alternate seats
+			names := []string{"duct", name}
+			opts := []*Opts{&duct, &o}
+			if i%2 == 1 {
+				names[0], names[1] = names[1], names[0]
+				opts[0], opts[1] = opts[1], opts[0]
+			}
+
+			winner := playArenaGame(t, 1000, opts...)
+			if winner < 0 {
+				wins["draw"]++
+			} else {
+				wins[names[winner]]++
+			}
+		}
+		fmt.Printf("duct vs %s: %v\n", name, wins)
+		require.Equal(t, 20, wins["duct"]+wins[name]+wins["draw"])
+		require.Greater(t, wins["duct"], wins[name], name)
+	}
+}
+
+// playArenaGame plays an 11x11 standard game between snakes using Once with
+// a fixed number of iterations per move and returns the winner index or -1 for a draw.
+func playArenaGame(t *testing.T, iterations int, opts ...*Opts) int {
+	t.Helper()
+
+	var ids []string
+	for i := range opts {
+		ids = append(ids, fmt.Sprint(i))
+	}
+
+	r := &rules.StandardRuleset{FoodSpawnChance: 15, MinimumFood: 1}
+	b, err := r.CreateInitialBoardState(11, 11, ids)
+	jtest.RequireNil(t, err)
+
+	for turn := 0; turn < 500; turn++ {
+		var moves []rules.SnakeMove
+		for i, o := range opts {
+			if b.Snakes[i].EliminatedCause != "" {
+				continue
+			}
+
+			root := NewRoot(r, b, i)
+			for j := 0; j < iterations; j++ {
+				jtest.RequireNil(t, Once(root, o))
+			}
+
+			move := root.RobustSafeMove(i)
+			if o.SampleFinal {
+				move = root.SampleMove(o, i)
+			}
+			moves = append(moves, rules.SnakeMove{ID: ids[i], Move: move})
+		}
+
+		b, err = r.CreateNextBoardState(b, moves)
+		jtest.RequireNil(t, err)
+
+		if over, err := r.IsGameOver(b); err != nil {
+			jtest.RequireNil(t, err)
+		} else if over {
+			break
+		}
+	}
+
+	for i, snake := range b.Snakes {
+		if snake.EliminatedCause == "" {
+			return i
+		}
+	}
+
+	return -1
+}
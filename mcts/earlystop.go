@@ -0,0 +1,52 @@
+package mcts
+
+import "time"
+
+// Early stop rules of SelectMove.
+const (
+	StopSingleMove = "single_move" // The root snake has a single rational move.
+	StopVisitLead  = "visit_lead"  // The most robust move can't be overtaken in the remaining time.
+	StopProven     = "proven"      // The root is proven, see MCTS-Solver, always applies if Solver is enabled.
+)
+
+// earlyStopInterval is the number of iterations between early stop checks.
+const earlyStopInterval = 64
+
+// StopRules are the early stop rules.
+var StopRules = []string{StopSingleMove, StopVisitLead, StopProven}
+
+// earlyStop returns the single move or visit lead early stop rule if it applies to the root
+// after elapsed of the budget.
+// The visit lead rule only applies to robust final move strategies.
+func (n *node) earlyStop(o *Opts, elapsed, budget time.Duration) (string, bool) {
+	if n.IsLeaf() {
+		return "", false
+	}
+
+	if len(n.RobustMoves(n.rootIdx)) == 1 {
+		return StopSingleMove, true
+	}
+
+	var first, second float64
+	visits, _ := n.moveStats(n.rootIdx)
+	for _, v := range visits {
+		if v > first {
+			first, second = v, first
+		} else if v > second {
+			second = v
+		}
+	}
+
+	if o.Final != FinalDefault && o.Final != FinalRobust {
+		return "", false
+	} else if elapsed <= 0 {
+		return "", false
+	}
+
+	remaining := n.n / elapsed.Seconds() * (budget - elapsed).Seconds()
+	if first-second > remaining {
+		return StopVisitLead, true
+	}
+
+	return "", false
+}
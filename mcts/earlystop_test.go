@@ -0,0 +1,48 @@
+package mcts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEarlyStop(t *testing.T) {
+	n := &node{n: 100, idsByIdx: []string{"a", "b"}}
+	for e, visits := range map[edge]float64{m2e(u, u): 45, m2e(u, d): 45, m2e(d, u): 5, m2e(d, d): 5} {
+		n.childs = append(n.childs, tuple{edge: e, child: &node{n: visits, totals: []float64{0, 0}, totalSquares: []float64{0, 0}}})
+	}
+
+	const budget = time.Millisecond * 340
+
+	_, ok := n.earlyStop(&Opts{}, time.Millisecond*100, budget)
+	require.False(t, ok)
+
+	rule, ok := n.earlyStop(&Opts{}, time.Millisecond*300, budget)
+	require.True(t, ok)
+	require.Equal(t, StopVisitLead, rule)
+
+	_, ok = n.earlyStop(&Opts{Final: FinalMaxAvg}, time.Millisecond*300, budget)
+	require.False(t, ok)
+}
+
+func TestEarlyStopSingleMove(t *testing.T) {
+	b, rootIdx := fileToBoard(t, "../testdata/input-017.json")
+	root := NewRoot(&rules.StandardRuleset{}, b, rootIdx)
+	jtest.RequireNil(t, Once(root, &OptsV5))
+
+	rule, ok := root.earlyStop(&OptsV5, time.Millisecond, time.Millisecond*340)
+	require.True(t, ok)
+	require.Equal(t, StopSingleMove, rule)
+
+	o := OptsV5
+	o.EarlyStop = true
+
+	move, stop, err := SelectMoveStop(context.Background(), b, nil, rootIdx, &o)
+	jtest.RequireNil(t, err)
+	require.Equal(t, "left", move)
+	require.Equal(t, StopSingleMove, stop)
+}
@@ -0,0 +1,152 @@
+package mcts
+
+import (
+	"math"
+	"time"
+
+	"github.com/corverroos/bsnake/board"
+)
+
+// Final defines the strategy used to select the final root move after search.
+type Final int
+
+const (
+	// FinalDefault selects the most robust move if Version is 1, otherwise the most robust safe move.
+	FinalDefault Final = 0
+
+	// FinalRobust selects the move with the most visits.
+	FinalRobust Final = 1
+
+	// FinalMaxAvg selects the move with the highest average score.
+	FinalMaxAvg Final = 2
+
+	// FinalSecure selects the move with the highest lower confidence bound, avg - SecureA/sqrt(n).
+	FinalSecure Final = 3
+
+	// FinalMaximin selects the move with the highest minimum average score over opponent replies.
+	FinalMaximin Final = 4
+
+	// FinalRobustMax selects the move with both the most visits and the highest average score,
+	// extending the search by up to FinalExtend until they agree, falling back to robust.
+	// The extension is reserved from the search budget, at most half of it.
+	FinalRobustMax Final = 5
+)
+
+func (f Final) String() string {
+	switch f {
+	case FinalDefault:
+		return "default"
+	case FinalRobust:
+		return "robust"
+	case FinalMaxAvg:
+		return "maxavg"
+	case FinalSecure:
+		return "secure"
+	case FinalMaximin:
+		return "maximin"
+	case FinalRobustMax:
+		return "robustmax"
+	default:
+		return "invalid"
+	}
+}
+
+// finalExtend returns the maximum search extension of FinalRobustMax, reserved from the budget.
+func (o *Opts) finalExtend() time.Duration {
+	if o.Final != FinalRobustMax {
+		return 0
+	} else if o.FinalExtend == 0 {
+		return time.Millisecond * 50
+	}
+	return o.FinalExtend
+}
+
+// FinalMove returns the root snake's final move according to Opts.Final.
+func (n *node) FinalMove(o *Opts, idx int) string {
+	switch o.Final {
+	case FinalDefault:
+		if o.Version == 1 {
+			return n.RobustMoves(idx)[0]
+		}
+		return n.RobustSafeMove(idx)
+	case FinalRobust:
+		return n.RobustMoves(idx)[0]
+	case FinalMaxAvg:
+		return n.MaxAvgMove(idx)
+	case FinalSecure:
+		a := o.SecureA
+		if a == 0 {
+			a = 1
+		}
+		return n.SecureMove(idx, a)
+	case FinalMaximin:
+		return n.MinMaxMove(idx)
+	case FinalRobustMax:
+		if move, ok := n.RobustMaxMove(idx); ok {
+			return move
+		}
+		return n.RobustMoves(idx)[0]
+	default:
+		panic("unknown final move strategy")
+	}
+}
+
+// moveStats returns the visits and score totals of the snake's moves in board.Moves order.
+func (n *node) moveStats(idx int) (visits []float64, totals []float64) {
+	visits = make([]float64, len(board.Moves))
+	totals = make([]float64, len(board.Moves))
+
+	for i, st := range n.getStats().moves[idx] {
+		visits[i] = st.n
+		totals[i] = st.totals
+	}
+
+	return visits, totals
+}
+
+// MaxAvgMove returns the visited move with the highest average score.
+func (n *node) MaxAvgMove(idx int) string {
+	return n.bestMove(idx, func(visits, total float64) float64 {
+		return total / visits
+	})
+}
+
+// SecureMove returns the visited move with the highest lower confidence bound.
+func (n *node) SecureMove(idx int, a float64) string {
+	return n.bestMove(idx, func(visits, total float64) float64 {
+		return total/visits - a/math.Sqrt(visits)
+	})
+}
+
+// RobustMaxMove returns the move with the most visits if it also has the highest average score.
+func (n *node) RobustMaxMove(idx int) (string, bool) {
+	robust := n.RobustMoves(idx)
+	if len(robust) == 0 {
+		return "", false
+	}
+
+	max := n.MaxAvgMove(idx)
+
+	return max, robust[0] == max
+}
+
+// bestMove returns the visited move with the highest score, ties broken by board.Moves order.
+func (n *node) bestMove(idx int, score func(visits, total float64) float64) string {
+	visits, totals := n.moveStats(idx)
+
+	var (
+		res string
+		max = math.Inf(-1)
+	)
+	for i, move := range board.Moves {
+		if visits[i] == 0 {
+			continue
+		}
+		if s := score(visits[i], totals[i]); s > max {
+			res = move
+			max = s
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,58 @@
+package mcts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinalMove(t *testing.T) {
+	// Root snake at idx 0, child visits and root snake totals.
+	childs := map[edge][2]float64{
+		m2e(u, u): {30, 15}, m2e(u, d): {30, -30}, // n=60 avg=-0.25  min=-1    lcb=-0.38
+		m2e(d, u): {10, 5}, m2e(d, d): {10, 0.5}, //  n=20 avg=0.275  min=0.05  lcb=0.05
+		m2e(l, u): {1, 1}, m2e(l, d): {1, 0}, //      n=2  avg=0.5    min=0     lcb=-0.21
+		m2e(r, u): {5, 2}, m2e(r, d): {5, 1.5}, //    n=10 avg=0.35   min=0.3   lcb=0.03
+	}
+
+	n := &node{idsByIdx: []string{"a", "b"}}
+	for e, c := range childs {
+		n.childs = append(n.childs, tuple{edge: e, child: &node{n: c[0], totals: []float64{c[1], 0}, totalSquares: []float64{0, 0}}})
+	}
+
+	tests := []struct {
+		Opts Opts
+		Exp  string
+	}{
+		{Opts: Opts{Version: 1}, Exp: "up"},
+		{Opts: Opts{Version: 2}, Exp: "down"}, // RobustSafe
+		{Opts: Opts{Final: FinalRobust}, Exp: "up"},
+		{Opts: Opts{Final: FinalMaxAvg}, Exp: "left"},
+		{Opts: Opts{Final: FinalSecure}, Exp: "down"},
+		{Opts: Opts{Final: FinalSecure, SecureA: 0.1}, Exp: "left"},
+		{Opts: Opts{Final: FinalMaximin}, Exp: "right"},
+		{Opts: Opts{Final: FinalRobustMax}, Exp: "up"}, // Fallback to robust
+	}
+
+	for _, test := range tests {
+		t.Run(test.Opts.Final.String(), func(t *testing.T) {
+			require.Equal(t, test.Exp, n.FinalMove(&test.Opts, 0))
+		})
+	}
+
+	_, ok := n.RobustMaxMove(0)
+	require.False(t, ok)
+
+	// Visit left until it is the most robust.
+	for _, tup := range n.childs {
+		if tup.edge.Is(0, "left") {
+			tup.child.n *= 40
+			tup.child.totals[0] *= 40
+		}
+	}
+	n.stats = nil
+
+	move, ok := n.RobustMaxMove(0)
+	require.True(t, ok)
+	require.Equal(t, "left", move)
+}
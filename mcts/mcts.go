@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"math/rand"
 	"time"
 
 	"github.com/BattlesnakeOfficial/rules"
@@ -23,40 +22,57 @@ import (
 //}
 
 func Once(root *node, o *Opts) error {
+	var seen raveMoves
+	if o.Rave {
+		seen = newRaveMoves(len(root.idsByIdx))
+	}
+
 	node := selection(root, o)
 	o.Logd("selected depth=%d", node.depth)
 
 	if node.IsTerminal() {
 		o.Logd("propagate old terminal")
-		propagation(node, node.termTotals)
+		propagation(node, node.termTotals, seen)
 		return nil
 	}
 
-	if node.n == 1 {
+	if o.Solver && node.proof != unproven {
+		o.Logd("propagate proven node, proof=%s", node.proof)
+		propagation(node, node.proofTotals(), seen)
+		return nil
+	}
+
+	full := root.treeFull(o)
+	if node.n == 1 && !full {
 		var err error
 		node, err = expansion(node, o)
 		if err != nil {
 			return err
 		}
+	} else if node.n == 1 {
+		o.Logd("tree full, evaluate leaf, nodes=%d", root.tree.nodes)
 	}
 
-	if node.n != 0 {
+	if node.n != 0 && !full {
 		panic("playout visited node")
 	}
 
-	if totals, ok, err := node.CheckTerminal(); err != nil {
+	if totals, ok, err := node.CheckTerminal(o); err != nil {
 		return err
 	} else if ok {
 		o.Logd("propagate new terminal, totals=%v", totals)
 		node.termTotals = totals
-		propagation(node, node.termTotals)
+		if o.Solver {
+			proveTerminal(node)
+		}
+		propagation(node, node.termTotals, seen)
 		return nil
 	}
 
 	var totals []float64
 	var err error
 	if o.LeafPlayout {
-		totals, err = playoutRandomRational(root, node, o)
+		totals, err = playoutRandomRational(root, node, o, seen)
 		if err != nil {
 			return err
 		}
@@ -68,7 +84,7 @@ func Once(root *node, o *Opts) error {
 		panic("invalid options, no leaf strategy")
 	}
 
-	propagation(node, totals)
+	propagation(node, totals, seen)
 
 	return nil
 }
@@ -81,7 +97,7 @@ func expansion(n *node, o *Opts) (*node, error) {
 	}
 
 	var res *node
-	moveSet := board.GenMoveSet(n.board)
+	moveSet := genMoveSet(n, o)
 
 	for i, moves := range moveSet {
 		child, err := n.AppendChild(moves)
@@ -103,7 +119,18 @@ func expansion(n *node, o *Opts) (*node, error) {
 	return res, nil
 }
 
-func playoutRandomRational(root, node *node, o *Opts) ([]float64, error) {
+// genMoveSet returns the move sets to expand n with. Snakes in Straight only move straight.
+// If AvoidLH2H is enabled, root node moves that risk a loosing head-to-head are pruned
+// if alternatives exist.
+func genMoveSet(n *node, o *Opts) [][]string {
+	moveSet := o.straightMoveSet(n.board, board.GenMoveSet(n.board))
+	if o.AvoidLH2H && n.parent == nil {
+		moveSet = board.PruneLoosingH2H(n.board, n.rootIdx, moveSet)
+	}
+	return moveSet
+}
+
+func playoutRandomRational(root, node *node, o *Opts, seen raveMoves) ([]float64, error) {
 	//defer lat("playout")()
 	l := len(root.idsByIdx)
 	b := node.board
@@ -114,33 +141,34 @@ func playoutRandomRational(root, node *node, o *Opts) ([]float64, error) {
 		maxcount = 100
 	}
 
-	randMoves := func(b *rules.BoardState) []rules.SnakeMove {
+	policyMoves := func(b *rules.BoardState) []rules.SnakeMove {
 		res := make([]rules.SnakeMove, l)
 		for i := 0; i < l; i++ {
 			if b.Snakes[i].EliminatedCause != "" {
 				continue
 			}
-			for j, move := range board.RandMoves() {
-				if j < 3 && !board.IsRationalMove(b, i, move) {
-					continue
-				}
-				res[i] = rules.SnakeMove{
-					ID:   b.Snakes[i].ID,
-					Move: move,
-				}
-				break
+			res[i] = rules.SnakeMove{
+				ID:   b.Snakes[i].ID,
+				Move: o.playoutPolicy(i, root.rootIdx).Move(b, i, o),
 			}
 		}
 		return res
 	}
 
 	greedyMoves := func(b *rules.BoardState) []rules.SnakeMove {
-		var res []rules.SnakeMove
+		res := make([]rules.SnakeMove, l)
 		for i := 0; i < l; i++ {
-			res = append(res, rules.SnakeMove{
+			if b.Snakes[i].EliminatedCause != "" {
+				continue
+			}
+			move := greedyMove(b, i, o)
+			if o.GreedyHeur != nil {
+				move = o.GreedyHeur(b, i)
+			}
+			res[i] = rules.SnakeMove{
 				ID:   b.Snakes[i].ID,
-				Move: o.GreedyHeur(b, i),
-			})
+				Move: move,
+			}
 		}
 		return res
 	}
@@ -150,17 +178,28 @@ func playoutRandomRational(root, node *node, o *Opts) ([]float64, error) {
 		startLens[i] = len(root.board.Snakes[i].Body)
 	}
 
+	var deaths []int
+	if o.shapedRewards() {
+		deaths = node.deaths()
+	}
+
 	var count int
 	res := make([]float64, l)
 	for {
 		var err error
 
-		moveFunc := randMoves
-		if rand.Float64() < o.GreedyProb {
+		moveFunc := policyMoves
+		if o.float64() < o.GreedyProb {
 			moveFunc = greedyMoves
 		}
 
 		moves := moveFunc(b)
+		o.straightenMoves(b, moves)
+		for i, move := range moves {
+			if move.Move != "" {
+				seen.add(i, move.Move)
+			}
+		}
 
 		b, err = r.CreateNextBoardState(b, moves)
 		if err != nil {
@@ -169,6 +208,12 @@ func playoutRandomRational(root, node *node, o *Opts) ([]float64, error) {
 
 		count++
 
+		for i := range deaths {
+			if deaths[i] == -1 && b.Snakes[i].EliminatedCause != "" {
+				deaths[i] = node.depth + count
+			}
+		}
+
 		over, err := r.IsGameOver(b)
 		if err != nil {
 			return nil, err
@@ -189,7 +234,9 @@ func playoutRandomRational(root, node *node, o *Opts) ([]float64, error) {
 			}
 		}
 
-		if over {
+		if over && deaths != nil {
+			return o.shapeRewards(res, deaths, node.depth+count), nil
+		} else if over {
 			return res, nil
 		}
 
@@ -329,76 +376,109 @@ func selection(root *node, o *Opts) *node {
 			return n
 		}
 
+		if o.Solver && n.proof != unproven && n.parent != nil {
+			return n
+		}
+
 		if n.n < o.SelectRandom {
-			random := n.childs[rand.Intn(len(n.childs))]
+			random := n.childs[o.intn(len(n.childs))]
 			n = random.child
 			o.Logd("select random child, depth=%d, edge=%s", n.depth, random.edge)
 			continue
 		}
 
-		type stats struct {
-			sumN       float64
-			sumTotals  float64
-			sumSquares float64
-			heuristic  float64
-		}
-
-		allStats := make([][]stats, len(n.idsByIdx))
+		stats := n.getStats()
 
-		// Calculate stats for each move for each snake
-		for _, tuple := range n.childs {
-			if tuple.child.n == 0 {
-				o.Logd("select unexplored child, depth=%d, edge=%s", tuple.child.depth, tuple.edge)
-				return tuple.child
+		if stats.explored < len(n.childs) {
+			for _, tuple := range n.childs {
+				if tuple.child.n == 0 {
+					o.Logd("select unexplored child, depth=%d, edge=%s", tuple.child.depth, tuple.edge)
+					return tuple.child
+				}
 			}
+		}
 
-			if o.SelectHeur && len(tuple.child.heurTotals) == 0 {
-				tuple.child.heurTotals = heur.Calc(o.HeurFactors, tuple.child.board, n.rootIdx, o.hazards)
+		if o.SelectHeur {
+			for _, tuple := range n.childs {
+				if len(tuple.child.heurTotals) == 0 {
+					tuple.child.heurTotals = heur.Calc(o.HeurFactors, tuple.child.board, n.rootIdx, o.hazards)
+					stats.addHeur(tuple.edge, tuple.child.heurTotals)
+				}
 			}
+		}
+
+		var losses map[string]bool
+		if o.Solver {
+			losses = n.provenLossMoves()
 
-			for i := 0; i < len(n.idsByIdx); i++ {
-				snakeStats := allStats[i]
-				if snakeStats == nil {
-					snakeStats = make([]stats, 4)
-					allStats[i] = snakeStats
+			var moves int
+			for _, st := range stats.moves[n.rootIdx] {
+				if st.n > 0 {
+					moves++
 				}
+			}
+			if len(losses) == moves {
+				// No alternatives
+				losses = nil
+			}
+		}
 
+		maxMoves := make([]string, len(n.idsByIdx))
+		for i := 0; i < len(n.idsByIdx); i++ {
+			if o.Bandit.isMixed() {
+				var avail []int
 				for midx, move := range board.Moves {
-					if !tuple.edge.Is(i, move) {
+					if stats.moves[i][midx].n == 0 || (i == n.rootIdx && losses[move]) {
 						continue
 					}
-					st := snakeStats[midx]
-					st.sumN += tuple.child.n
-					st.sumTotals += tuple.child.totals[i]
-					st.sumSquares += tuple.child.totalSquares[i]
-					st.heuristic += tuple.child.heurTotals[i]
-					snakeStats[midx] = st
-					break
+					avail = append(avail, midx)
+				}
+				if len(avail) > 0 {
+					maxMoves[i] = board.Moves[n.sampleBandit(o, i, avail)]
 				}
+				continue
 			}
-		}
 
-		maxMoves := make([]string, len(n.idsByIdx))
-		for i := 0; i < len(n.idsByIdx); i++ {
 			var max *float64
 			for midx, move := range board.Moves {
-				st := allStats[i][midx]
-				if st.sumN == 0 {
+				st := stats.moves[i][midx]
+				if st.n == 0 {
 					continue
 				}
 
-				c := o.UCB1_C
-				if o.Tuned && st.sumN > 1 {
-					// UCB1-Tuned: https://dke.maastrichtuniversity.nl/m.winands/documents/sm-tron-bnaic2013.pdf
-					variance := (st.sumSquares - (st.sumTotals*st.sumTotals)/st.sumN) / (st.sumN - 1)
+				if i == n.rootIdx && losses[move] {
+					// Skip proven losses
+					continue
+				}
+
+				mean := st.totals / st.n
+				if o.Rave {
+					mean = n.raveMean(o, i, midx, st.n, mean)
+				}
+
+				var score float64
+				switch o.Bandit {
+				case BanditUCB1:
+					c := o.UCB1_C
+					if o.Tuned && st.n > 1 {
+						// UCB1-Tuned: https://dke.maastrichtuniversity.nl/m.winands/documents/sm-tron-bnaic2013.pdf
+						variance := (st.squares - (st.totals*st.totals)/st.n) / (st.n - 1)
+
+						v := variance + math.Sqrt(2*math.Log(n.n)/st.n)
+						c *= math.Min(0.25, v)
+					}
 
-					v := variance + math.Sqrt(2*math.Log(n.n)/st.sumN)
-					c *= math.Min(0.25, v)
+					score = mean + math.Sqrt(c*math.Log(n.n)/st.n) + st.heur/(st.n+1)
+				case BanditPUCT:
+					// PUCT: https://www.nature.com/articles/nature24270
+					prior := n.Priors(o, i)[midx]
+					score = mean + o.puctC()*prior*math.Sqrt(n.n)/(1+st.n)
+				default:
+					panic("unknown bandit")
 				}
 
-				ucb1 := st.sumTotals/st.sumN + math.Sqrt(c*math.Log(n.n)/st.sumN) + st.heuristic/(st.sumN+1)
-				if max == nil || ucb1 > *max {
-					max = &ucb1
+				if max == nil || score > *max {
+					max = &score
 					maxMoves[i] = move
 				}
 			}
@@ -425,7 +505,7 @@ func selection(root *node, o *Opts) *node {
 	}
 }
 
-func propagation(node *node, totals []float64) {
+func propagation(node *node, totals []float64, seen raveMoves) {
 	n := node
 	for n != nil {
 		for idx, t := range totals {
@@ -433,46 +513,129 @@ func propagation(node *node, totals []float64) {
 			n.totalSquares[idx] += t * t
 		}
 		n.n++
+		if p := n.parent; p != nil && p.stats != nil {
+			p.stats.addVisit(n.edge, totals, n.n == 1)
+		}
+		n.updateBandits(totals)
+		n.updateRave(totals, seen)
 		n = n.parent
 	}
 }
 
 func SelectMove(ctx context.Context, board *rules.BoardState, hazards []rules.Point, rootIDx int, o *Opts) (string, error) {
+	move, _, err := SelectMoveStop(ctx, board, hazards, rootIDx, o)
+	return move, err
+}
+
+// SelectMoveStop returns the move of the root snake like SelectMove and the early stop
+// rule of the search, empty if it searched for the whole budget, see EarlyStop.
+func SelectMoveStop(ctx context.Context, board *rules.BoardState, hazards []rules.Point, rootIDx int, o *Opts) (string, string, error) {
 	t0 := time.Now()
 
-	var ruleset rules.Ruleset
+	o.hazards = make(map[rules.Point]bool)
+	for _, hazard := range hazards {
+		o.hazards[hazard] = true
+	}
+
+	root := NewRoot(newRuleset(board, hazards), board, rootIDx)
+	defer root.release()
+
+	return search(t0, budget(ctx, t0), root, rootIDx, o)
+}
+
+const (
+	// defaultBudget is the search budget of a move without a context deadline.
+	defaultBudget = time.Millisecond * 340
+
+	// deadlineMargin is the time reserved before the context deadline to respond,
+	// at most a third of the time remaining.
+	deadlineMargin = time.Millisecond * 160
+)
+
+// budget returns the search budget of a move started at t0; the time until the context
+// deadline less the margin, or the default budget if the context has no deadline.
+func budget(ctx context.Context, t0 time.Time) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return defaultBudget
+	}
+
+	remaining := deadline.Sub(t0)
+	margin := deadlineMargin
+	if remaining < 3*margin {
+		margin = remaining / 3
+	}
+
+	return remaining - margin
+}
+
+// newRuleset returns the ruleset for the board.
+func newRuleset(board *rules.BoardState, hazards []rules.Point) rules.Ruleset {
 	if len(board.Snakes) == 1 {
-		ruleset = &rules.SoloRuleset{}
+		return &rules.SoloRuleset{}
 	} else if len(hazards) > 0 {
-		ruleset = &RoyaleRuleset{
+		return &RoyaleRuleset{
 			Hazards: hazards,
 		}
-	} else {
-		ruleset = &rules.StandardRuleset{}
 	}
+	return &rules.StandardRuleset{}
+}
 
-	o.hazards = make(map[rules.Point]bool)
-	for _, hazard := range hazards {
-		o.hazards[hazard] = true
+// search runs MCTS iterations from root until the budget since t0 is exhausted
+// and returns the final move of the root snake and the early stop rule, empty if it
+// searched for the whole budget. The FinalRobustMax extension is reserved from the
+// end of the budget.
+func search(t0 time.Time, budget time.Duration, root *node, rootIDx int, o *Opts) (string, string, error) {
+	var stop string
+	extend := o.finalExtend()
+	if extend > budget/2 {
+		extend = budget / 2
+	}
+
+	for i := 0; time.Since(t0) < budget-extend; i++ {
+		err := Once(root, o)
+		if err != nil {
+			return "", "", err
+		}
+
+		if o.Solver && root.proof != unproven {
+			stop = StopProven
+			break
+		}
+
+		if !o.EarlyStop || i%earlyStopInterval != 0 {
+			continue
+		}
+
+		if rule, ok := root.earlyStop(o, time.Since(t0), budget-extend); ok {
+			o.Logd("early stop, rule=%s, iterations=%d", rule, i)
+			stop = rule
+			break
+		}
 	}
 
-	root := NewRoot(ruleset, board, rootIDx)
+	for o.Final == FinalRobustMax && time.Since(t0) < budget {
+		if _, ok := root.RobustMaxMove(rootIDx); ok {
+			break
+		}
 
-	for time.Since(t0) < time.Millisecond*340 {
 		err := Once(root, o)
 		if err != nil {
-			return "", err
+			return "", "", err
 		}
 	}
 
-	var move string
-	if o.Version == 1 {
-		move = root.RobustMoves(rootIDx)[0]
-	} else {
-		move = root.RobustSafeMove(rootIDx)
+	move := root.FinalMove(o, rootIDx)
+
+	if o.SampleFinal {
+		move = root.SampleMove(o, rootIDx)
+	}
+
+	if o.Solver {
+		move = root.SolvedMove(move)
 	}
 
 	o.LogResults(root, rootIDx, move)
 
-	return move, nil
+	return move, stop, nil
 }
@@ -1,8 +1,10 @@
 package mcts
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/BattlesnakeOfficial/rules"
 	"github.com/luno/jettison/jtest"
@@ -160,3 +162,172 @@ func m2e(ml ...int) edge {
 	}
 	return newEdge(res)
 }
+
+// TestMxBackups compares the root decisions of the backup rules on multi-snake fixtures,
+// see TestMxBackupValues for the values.
+func TestMxBackups(t *testing.T) {
+	files := []string{
+		"../testdata/input-007.json",
+		"../testdata/input-010.json",
+		"../testdata/input-022.json",
+		"../testdata/input-033.json",
+		"../testdata/input-027.json", // Two snakes
+	}
+
+	backups := []Backup{BackupMaximin, BackupMaxN, BackupParanoid, BackupBestReply}
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			b, rootIdx := fileToBoard(t, file)
+
+			minimax := make(map[Backup]mx)
+			for _, backup := range backups {
+				o := OptsV2
+				o.MxBackup = backup
+
+				root := NewRoot(&rules.StandardRuleset{}, b, rootIdx)
+				mxl, err := Minimax(root, &o, nil, 2)
+				jtest.RequireNil(t, err)
+				minimax[backup] = mxl[rootIdx]
+			}
+
+			// Paranoid and maximin root snake values are equivalent.
+			require.Equal(t, minimax[BackupMaximin], minimax[BackupParanoid])
+
+			if len(b.Snakes) == 2 {
+				// A single opponent always replies.
+				require.Equal(t, minimax[BackupParanoid], minimax[BackupBestReply])
+			}
+		})
+	}
+}
+
+func TestMxBackupValues(t *testing.T) {
+	// Child totals by edge of the root snake a at idx 0 and opponents b and c.
+	childs := map[edge][]float64{
+		m2e(u, u, u): {0.8, 0.5, 0.5}, m2e(u, u, d): {-1, 0.1, 0.2},
+		m2e(u, d, u): {0.6, 0.2, 0.3}, m2e(u, d, d): {0.7, 0.3, 0.1},
+		m2e(d, u, u): {0.3, 0.4, 0.6}, m2e(d, u, d): {0.2, 0.4, 0.1},
+		m2e(d, d, u): {0.1, 0.5, 0.5}, m2e(d, d, d): {0.4, 0.2, 0.2},
+	}
+
+	tests := []struct {
+		Backup Backup
+		Moves  []string
+		Values []float64
+	}{
+		{
+			// Each snake's worst case independently.
+			Backup: BackupMaximin,
+			Moves:  []string{"down", "down", "up"},
+			Values: []float64{0.1, 0.2, 0.3},
+		},
+		{
+			// c replies up to both b moves, so b moves up after a up, a expects 0.8.
+			Backup: BackupMaxN,
+			Moves:  []string{"up", "up", "up"},
+			Values: []float64{0.8, 0.5, 0.5},
+		},
+		{
+			// b and c collude against a up (-1), so a moves down.
+			Backup: BackupParanoid,
+			Moves:  []string{"down", "down", "up"},
+			Values: []float64{0.1, 0.5, 0.5},
+		},
+		{
+			// Only one of b and c deviates from its maximin move (down, up), so a up
+			// avoids the -1 of both deviating.
+			Backup: BackupBestReply,
+			Moves:  []string{"up", "down", "up"},
+			Values: []float64{0.6, 0.2, 0.3},
+		},
+		{
+			// Opponents move up with probability 0.75.
+			Backup: BackupExpectimax,
+			Moves:  []string{"up", "up", "up"},
+			Values: []float64{0.41875, 0.35625, 0.38125},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Backup.String(), func(t *testing.T) {
+			n := &node{
+				board:      &rules.BoardState{Snakes: []rules.Snake{{ID: "a"}, {ID: "b"}, {ID: "c"}}},
+				idsByIdx:   []string{"a", "b", "c"},
+				totals:     make([]float64, 3),
+				heurTotals: make([]float64, 3),
+			}
+			for e, totals := range childs {
+				n.childs = append(n.childs, tuple{edge: e, child: &node{n: 1, totals: totals}})
+			}
+
+			o := Opts{MxBackup: test.Backup, Opponents: fixedPolicy{0.75, 0.25, 0, 0}}
+			res := MxPropagate(n, &o)
+			for i := range res {
+				require.Equal(t, test.Moves[i], res[i].move, "snake %d", i)
+				require.InDelta(t, test.Values[i], res[i].minimax, 1e-9, "snake %d", i)
+				require.InDelta(t, test.Values[i], n.totals[i], 1e-9, "snake %d", i)
+			}
+		})
+	}
+}
+
+func TestSelectMxDeadline(t *testing.T) {
+	b, rootIdx := fileToBoard(t, "../testdata/input-022.json")
+
+	t0 := time.Now()
+	ctx, cancel := context.WithDeadline(context.Background(), t0.Add(time.Millisecond*90))
+	defer cancel()
+
+	o := OptsV2
+	move, err := SelectMx(ctx, b, nil, rootIdx, &o)
+	jtest.RequireNil(t, err)
+	require.NotEmpty(t, move)
+	require.Less(t, int64(time.Since(t0)), int64(time.Millisecond*90))
+}
+
+// fixedPolicy assigns the same priors to all snakes.
+type fixedPolicy []float64
+
+func (p fixedPolicy) Priors(*rules.BoardState, int, map[rules.Point]bool) []float64 {
+	return p
+}
+
+func TestMxExpectimax(t *testing.T) {
+	// Root snake at idx 0, child root snake totals.
+	childs := map[edge]float64{
+		m2e(u, u): 0.5, m2e(u, d): -1, // Expected 0.35, worst -1
+		m2e(d, u): 0.2, m2e(d, d): 0.2, // Expected 0.2, worst 0.2
+	}
+
+	newNode := func() *node {
+		n := &node{
+			board:      &rules.BoardState{Snakes: []rules.Snake{{ID: "a"}, {ID: "b"}}},
+			idsByIdx:   []string{"a", "b"},
+			totals:     make([]float64, 2),
+			heurTotals: make([]float64, 2),
+		}
+		for e, total := range childs {
+			n.childs = append(n.childs, tuple{edge: e, child: &node{n: 1, totals: []float64{total, -total}}})
+		}
+		return n
+	}
+
+	// Opponent moves up with probability 0.9, see board.Moves order.
+	o := Opts{MxBackup: BackupExpectimax, Opponents: fixedPolicy{0.9, 0.1, 0, 0}}
+	res := MxPropagate(newNode(), &o)
+	require.Equal(t, "up", res[0].move)
+	require.InDelta(t, 0.35, res[0].minimax, 1e-9)
+	require.Equal(t, "up", res[1].move)
+	require.InDelta(t, -0.35, res[1].minimax, 1e-9)
+
+	// Indifferent opponent.
+	o.Opponents = fixedPolicy{0.5, 0.5, 0, 0}
+	res = MxPropagate(newNode(), &o)
+	require.Equal(t, "down", res[0].move)
+	require.InDelta(t, 0.2, res[0].minimax, 1e-9)
+
+	o.MxBackup = BackupParanoid
+	res = MxPropagate(newNode(), &o)
+	require.Equal(t, "down", res[0].move)
+}
@@ -1,6 +1,7 @@
 package mcts
 
 import (
+	"context"
 	"time"
 
 	"github.com/BattlesnakeOfficial/rules"
@@ -14,8 +15,8 @@ type mx struct {
 	minimax float64
 }
 
-func Minimax(n *node, f *heur.Factors, hazards map[rules.Point]bool, ply int) ([]mx, error) {
-	for _, moves := range board.GenMoveSet(n.board) {
+func Minimax(n *node, o *Opts, hazards map[rules.Point]bool, ply int) ([]mx, error) {
+	for _, moves := range genMoveSet(n, o) {
 		tup, err := genChild(n, moves)
 		if err != nil {
 			return nil, err
@@ -30,7 +31,7 @@ func Minimax(n *node, f *heur.Factors, hazards map[rules.Point]bool, ply int) ([
 
 		n.childs = append(n.childs, tup)
 
-		if totals, ok, err := child.CheckTerminal(); err != nil {
+		if totals, ok, err := child.CheckTerminal(o); err != nil {
 			return nil, err
 		} else if ok {
 			child.termTotals = totals
@@ -40,70 +41,377 @@ func Minimax(n *node, f *heur.Factors, hazards map[rules.Point]bool, ply int) ([
 		}
 
 		if ply == 1 {
-			totals := heur.Calc(f, child.board, child.rootIdx, hazards)
+			totals := heur.Calc(o.HeurFactors, child.board, child.rootIdx, hazards)
 			child.heurTotals = totals
 			child.totals = totals
 			child.n++
 		} else {
-			_, err := Minimax(child, f, hazards, ply-1)
+			_, err := Minimax(child, o, hazards, ply-1)
 			if err != nil {
 				return nil, err
 			}
 		}
 	}
 
-	return MxPropagate(n), nil
+	return MxPropagate(n, o), nil
 }
 
-func MxPropagate(n *node) []mx {
+// Backup defines how multiplayer minimax values are propagated up the tree.
+type Backup int
 
+const (
+	// BackupMaximin assigns each snake its own maximin value independently.
+	BackupMaximin Backup = 0
+
+	// BackupMaxN assigns the values of the child selected by each snake in turn
+	// maximising its own value, root snake first (sequential max^n).
+	BackupMaxN Backup = 1
+
+	// BackupParanoid assigns the values of the child selected by the root snake
+	// maximising its value with all opponents minimising it.
+	BackupParanoid Backup = 2
+
+	// BackupBestReply assigns the root snake the maximum over its moves of the
+	// single best opponent reply, with the other opponents playing their maximin moves.
+	BackupBestReply Backup = 3
+
+	// BackupExpectimax assigns the expected values of the root snake's maximising move
+	// with opponent moves weighted by their priors, see Opts.Opponents.
+	BackupExpectimax Backup = 4
+)
+
+func (b Backup) String() string {
+	switch b {
+	case BackupMaximin:
+		return "maximin"
+	case BackupMaxN:
+		return "maxn"
+	case BackupParanoid:
+		return "paranoid"
+	case BackupBestReply:
+		return "bestreply"
+	case BackupExpectimax:
+		return "expectimax"
+	default:
+		return "unknown"
+	}
+}
+
+// MxPropagate sets the totals of n from its children using the backup rule in o
+// and returns the selected move and value of each snake.
+func MxPropagate(n *node, o *Opts) []mx {
+	var res []mx
+	switch o.MxBackup {
+	case BackupMaximin:
+		res = mxMaximin(n)
+	case BackupMaxN:
+		res = mxVector(n, mxMaxN(n))
+	case BackupParanoid:
+		res = mxVector(n, mxParanoid(n))
+	case BackupBestReply:
+		res = mxBestReply(n)
+	case BackupExpectimax:
+		res = mxExpectimax(n, o)
+	default:
+		panic("unknown backup")
+	}
+
+	n.n++
+
+	// Totals are replaced, not aggregated, so rebuild cached stats when needed.
+	n.stats = nil
+	if n.parent != nil {
+		n.parent.stats = nil
+	}
+
+	return res
+}
+
+func mxMaximin(n *node) []mx {
 	res := make([]mx, len(n.board.Snakes))
 
 	for i := 0; i < len(n.board.Snakes); i++ {
-		var maxMove string
-		var maxScore float64
+		move, score, ok := maximin(n, i)
+		if ok {
+			res[i] = mx{
+				move:    move,
+				minimax: score,
+			}
+			n.heurTotals[i] = score
+			n.totals[i] = score
+		}
+	}
+
+	return res
+}
+
+// maximin returns the move of snake i with the maximum worst case child total.
+func maximin(n *node, i int) (string, float64, bool) {
+	var maxMove string
+	var maxScore float64
+	for _, move := range board.Moves {
+
+		var min *float64
+		for j := 0; j < len(n.childs); j++ {
+			tup := &n.childs[j]
+			if !tup.edge.Is(i, move) {
+				continue
+			}
+
+			total := tup.child.totals[i]
+
+			if min == nil || total < *min {
+				min = &total
+			}
+		}
+
+		if min != nil && (maxMove == "" || maxScore < *min) {
+			maxMove = move
+			maxScore = *min
+		}
+	}
+
+	return maxMove, maxScore, maxMove != ""
+}
+
+// mxVector sets the totals of n to those of the selected child and returns
+// the moves of the selected child's edge.
+func mxVector(n *node, tup *tuple) []mx {
+	res := make([]mx, len(n.board.Snakes))
+	if tup == nil {
+		return res
+	}
+
+	for i := 0; i < len(n.board.Snakes); i++ {
 		for _, move := range board.Moves {
+			if tup.edge.Is(i, move) {
+				res[i].move = move
+				break
+			}
+		}
+		res[i].minimax = tup.child.totals[i]
+		n.heurTotals[i] = tup.child.totals[i]
+		n.totals[i] = tup.child.totals[i]
+	}
 
-			var min *float64
-			for j := 0; j < len(n.childs); j++ {
-				tup := &n.childs[j]
-				if !tup.edge.Is(i, move) {
-					continue
-				}
+	return res
+}
+
+// mxMaxN returns the child selected by alive snakes in turn, root snake first,
+// each maximising its own total given the best responses of the snakes after it.
+func mxMaxN(n *node) *tuple {
+	order := []int{n.rootIdx}
+	for i := 0; i < len(n.board.Snakes); i++ {
+		if i != n.rootIdx && n.board.Snakes[i].EliminatedCause == "" {
+			order = append(order, i)
+		}
+	}
+
+	cands := make([]*tuple, 0, len(n.childs))
+	for j := 0; j < len(n.childs); j++ {
+		cands = append(cands, &n.childs[j])
+	}
 
-				total := tup.child.totals[i]
+	var maxn func(cands []*tuple, k int) *tuple
+	maxn = func(cands []*tuple, k int) *tuple {
+		if len(cands) == 0 {
+			return nil
+		} else if k == len(order) {
+			return cands[0]
+		}
 
-				if min == nil || total < *min {
-					min = &total
+		i := order[k]
+
+		var res *tuple
+		for _, move := range board.Moves {
+			var group []*tuple
+			for _, tup := range cands {
+				if tup.edge.Is(i, move) {
+					group = append(group, tup)
 				}
 			}
 
-			if min != nil && (maxMove == "" || maxScore < *min) {
-				maxMove = move
-				maxScore = *min
+			tup := maxn(group, k+1)
+			if tup == nil {
+				continue
+			}
+
+			if res == nil || tup.child.totals[i] > res.child.totals[i] {
+				res = tup
 			}
 		}
 
-		if maxMove != "" {
-			res[i] = mx{
-				move:    maxMove,
-				minimax: maxScore,
+		return res
+	}
+
+	return maxn(cands, 0)
+}
+
+// mxParanoid returns the child with the maximum root snake worst case total.
+func mxParanoid(n *node) *tuple {
+	i := n.rootIdx
+
+	var res *tuple
+	for _, move := range board.Moves {
+		var min *tuple
+		for j := 0; j < len(n.childs); j++ {
+			tup := &n.childs[j]
+			if !tup.edge.Is(i, move) {
+				continue
 			}
-			n.heurTotals[i] = maxScore
-			n.totals[i] = maxScore
+			if min == nil || tup.child.totals[i] < min.child.totals[i] {
+				min = tup
+			}
+		}
+
+		if min != nil && (res == nil || min.child.totals[i] > res.child.totals[i]) {
+			res = min
 		}
 	}
 
-	n.n++
+	return res
+}
+
+// mxBestReply propagates the root snake value of a best-reply search: for each root
+// move, only one opponent replies to minimise the root total while the rest play
+// their maximin moves. Opponents are assigned their maximin values.
+func mxBestReply(n *node) []mx {
+	res := mxMaximin(n)
+	i := n.rootIdx
+
+	var maxMove string
+	var maxScore float64
+	for _, move := range board.Moves {
+		var min *float64
+		for j := 0; j < len(n.childs); j++ {
+			tup := &n.childs[j]
+			if !tup.edge.Is(i, move) || !isBestReply(tup.edge, res, i) {
+				continue
+			}
+
+			total := tup.child.totals[i]
+			if min == nil || total < *min {
+				min = &total
+			}
+		}
+
+		if min != nil && (maxMove == "" || maxScore < *min) {
+			maxMove = move
+			maxScore = *min
+		}
+	}
+
+	if maxMove == "" {
+		return res
+	}
+
+	res[i] = mx{
+		move:    maxMove,
+		minimax: maxScore,
+	}
+	n.heurTotals[i] = maxScore
+	n.totals[i] = maxScore
 
 	return res
 }
 
+// mxExpectimax propagates the expected child totals of the root snake move with the
+// maximum expected root total. Children are weighted by the product of the opponent
+// move priors. Opponents are assigned their most probable moves.
+func mxExpectimax(n *node, o *Opts) []mx {
+	res := make([]mx, len(n.board.Snakes))
+	i := n.rootIdx
+
+	var (
+		maxMove   string
+		maxTotals []float64
+	)
+	for _, move := range board.Moves {
+		var weights float64
+		totals := make([]float64, len(n.board.Snakes))
+		for j := 0; j < len(n.childs); j++ {
+			tup := &n.childs[j]
+			if !tup.edge.Is(i, move) {
+				continue
+			}
+
+			w := 1.0
+			for k := range n.board.Snakes {
+				if midx := tup.edge.moveIdx(k); k != i && midx >= 0 {
+					w *= n.Priors(o, k)[midx]
+				}
+			}
+			if w == 0 {
+				continue
+			}
+
+			weights += w
+			for k := range totals {
+				totals[k] += w * tup.child.totals[k]
+			}
+		}
+
+		if weights == 0 {
+			continue
+		}
+		for k := range totals {
+			totals[k] /= weights
+		}
+
+		if maxMove == "" || totals[i] > maxTotals[i] {
+			maxMove = move
+			maxTotals = totals
+		}
+	}
+
+	if maxMove == "" {
+		return res
+	}
+
+	for k := range res {
+		if k == i {
+			res[k].move = maxMove
+		} else if n.board.Snakes[k].EliminatedCause == "" {
+			res[k].move = mostProbable(n.Priors(o, k))
+		}
+		res[k].minimax = maxTotals[k]
+		n.heurTotals[k] = maxTotals[k]
+		n.totals[k] = maxTotals[k]
+	}
+
+	return res
+}
+
+// mostProbable returns the move with the highest prior, ties broken by board.Moves order.
+func mostProbable(priors []float64) string {
+	var res int
+	for midx, p := range priors {
+		if p > priors[res] {
+			res = midx
+		}
+	}
+	return board.Moves[res]
+}
+
+// isBestReply returns true if at most one opponent deviates from its maximin move in the edge.
+func isBestReply(e edge, maximins []mx, rootIdx int) bool {
+	var deviations int
+	for i, m := range maximins {
+		if i == rootIdx || m.move == "" {
+			continue
+		}
+		if !e.Is(i, m.move) {
+			deviations++
+		}
+	}
+	return deviations <= 1
+}
+
 func MxOnce(root *node, o *Opts, hazards map[rules.Point]bool) ([]mx, error) {
 	n := selection(root, o)
 
 	if !n.IsTerminal() {
-		res, err := Minimax(n, o.HeurFactors, hazards, 1)
+		res, err := Minimax(n, o, hazards, 1)
 		if err != nil {
 			return nil, err
 		}
@@ -114,37 +422,29 @@ func MxOnce(root *node, o *Opts, hazards map[rules.Point]bool) ([]mx, error) {
 
 	for {
 		n = n.parent
-		res := MxPropagate(n)
+		res := MxPropagate(n, o)
 		if n.parent == nil {
 			return res, nil
 		}
 	}
 }
 
-func SelectMx(board *rules.BoardState, hazards []rules.Point, rootIDx int, o *Opts) (string, error) {
+// SelectMx returns the root snake move of minimax tree search iterations that run
+// until the search budget of the context deadline is exhausted, at least one.
+func SelectMx(ctx context.Context, board *rules.BoardState, hazards []rules.Point, rootIDx int, o *Opts) (string, error) {
 	t0 := time.Now()
-
-	var ruleset rules.Ruleset
-	if len(board.Snakes) == 1 {
-		ruleset = &rules.SoloRuleset{}
-	} else if len(hazards) > 0 {
-		ruleset = &RoyaleRuleset{
-			Hazards: hazards,
-		}
-	} else {
-		ruleset = &rules.StandardRuleset{}
-	}
+	b := budget(ctx, t0)
 
 	hazmap := make(map[rules.Point]bool)
 	for _, hazard := range hazards {
 		hazmap[hazard] = true
 	}
 
-	root := NewRoot(ruleset, board, rootIDx)
+	root := NewRoot(newRuleset(board, hazards), board, rootIDx)
 
 	var moves []mx
-	var err error
-	for time.Since(t0) < time.Millisecond*340 {
+	for moves == nil || time.Since(t0) < b {
+		var err error
 		moves, err = MxOnce(root, o, hazmap)
 		if err != nil {
 			return "", err
@@ -154,29 +454,26 @@ func SelectMx(board *rules.BoardState, hazards []rules.Point, rootIDx int, o *Op
 	return moves[rootIDx].move, nil
 }
 
-func SelectMinimax(board *rules.BoardState, hazards []rules.Point, rootIDx int, f *heur.Factors, ply int) (string, error) {
-	var ruleset rules.Ruleset
-	if len(board.Snakes) == 1 {
-		ruleset = &rules.SoloRuleset{}
-	} else if len(hazards) > 0 {
-		ruleset = &RoyaleRuleset{
-			Hazards: hazards,
-		}
-	} else {
-		ruleset = &rules.StandardRuleset{}
-	}
+// SelectMinimax returns the root snake move of an iterative deepening alpha-beta
+// search with the options' heuristic factors that runs until the search budget of
+// the context deadline is exhausted.
+func SelectMinimax(ctx context.Context, board *rules.BoardState, hazards []rules.Point, rootIDx int, o *Opts) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, budget(ctx, time.Now()))
+	defer cancel()
 
 	hazmap := make(map[rules.Point]bool)
 	for _, hazard := range hazards {
 		hazmap[hazard] = true
 	}
 
-	root := NewRoot(ruleset, board, rootIDx)
+	root := NewRoot(newRuleset(board, hazards), board, rootIDx)
 
-	res, err := Minimax(root, f, hazmap, ply)
+	res, err := AlphaBeta(ctx, root, o, hazmap, maxABDepth)
 	if err != nil {
 		return "", err
 	}
 
-	return res[rootIDx].move, nil
+	o.Logd("minimax: depth=%d score=%.3f nodes=%d", res.Depth, res.Score, res.Nodes)
+
+	return res.Move, nil
 }
@@ -0,0 +1,89 @@
+package mcts
+
+// moveStat is the aggregate of the children of a node with the same snake move.
+type moveStat struct {
+	n       float64 // Sum of child visits
+	totals  float64 // Sum of child totals
+	squares float64 // Sum of child total squares
+	heur    float64 // Sum of child heuristic totals
+	childs  int     // Number of children
+}
+
+// nodeStats are the incrementally updated child aggregates of a node.
+type nodeStats struct {
+	moves    [][4]moveStat // By snake, by board.Moves index
+	explored int           // Number of visited children
+}
+
+// edgeMoveIdx maps the 3 bit snake move of an edge to its board.Moves index.
+var edgeMoveIdx = [8]int{-1, 0, 1, 3, 2, -1, -1, -1}
+
+// moveIdx returns the board.Moves index of the snake's move or -1 if it has none.
+func (e edge) moveIdx(idx int) int {
+	return edgeMoveIdx[(e>>(idx*3))&0b111]
+}
+
+// getStats returns the child aggregates of n, building them from the children if
+// they are not cached.
+func (n *node) getStats() *nodeStats {
+	if n.stats != nil {
+		return n.stats
+	}
+
+	n.stats = &nodeStats{moves: make([][4]moveStat, len(n.idsByIdx))}
+	for _, tup := range n.childs {
+		n.stats.addChild(tup.edge)
+		if tup.child.n == 0 {
+			continue
+		}
+		n.stats.explored++
+		for i := range n.stats.moves {
+			midx := tup.edge.moveIdx(i)
+			if midx < 0 {
+				continue
+			}
+			st := &n.stats.moves[i][midx]
+			st.n += tup.child.n
+			st.totals += tup.child.totals[i]
+			st.squares += tup.child.totalSquares[i]
+			if len(tup.child.heurTotals) > 0 {
+				st.heur += tup.child.heurTotals[i]
+			}
+		}
+	}
+
+	return n.stats
+}
+
+// addChild adds a new child with the edge.
+func (s *nodeStats) addChild(e edge) {
+	for i := range s.moves {
+		if midx := e.moveIdx(i); midx >= 0 {
+			s.moves[i][midx].childs++
+		}
+	}
+}
+
+// addVisit adds a visit of the child with the edge and totals. The child was unexplored if first.
+func (s *nodeStats) addVisit(e edge, totals []float64, first bool) {
+	if first {
+		s.explored++
+	}
+	for i, t := range totals {
+		if midx := e.moveIdx(i); midx >= 0 {
+			st := &s.moves[i][midx]
+			st.n++
+			st.totals += t
+			st.squares += t * t
+		}
+	}
+}
+
+// addHeur adds the heuristic totals of the child with the edge.
+func (s *nodeStats) addHeur(e edge, heurTotals []float64) {
+	for i, h := range heurTotals {
+		if midx := e.moveIdx(i); midx >= 0 {
+			s.moves[i][midx].heur += h
+		}
+	}
+}
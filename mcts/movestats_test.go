@@ -0,0 +1,75 @@
+package mcts
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveStatsCache(t *testing.T) {
+	for _, file := range []string{"../testdata/input-022.json", "../testdata/input-023.json"} {
+		t.Run(file, func(t *testing.T) {
+			b, rootIdx := fileToBoard(t, file)
+
+			o := OptsV5
+			o.Solver = true
+
+			root := NewRoot(&rules.StandardRuleset{}, b, rootIdx)
+			for i := 0; i < 2000; i++ {
+				rand.Seed(int64(i))
+				jtest.RequireNil(t, Once(root, &o))
+			}
+
+			var check func(n *node)
+			check = func(n *node) {
+				if n.stats == nil {
+					return
+				}
+				cached := n.stats
+				n.stats = nil
+				rebuilt := n.getStats()
+				require.Equal(t, rebuilt.explored, cached.explored)
+				for i := range rebuilt.moves {
+					for midx, st := range rebuilt.moves[i] {
+						require.Equal(t, st.n, cached.moves[i][midx].n)
+						require.Equal(t, st.childs, cached.moves[i][midx].childs)
+						require.InDelta(t, st.totals, cached.moves[i][midx].totals, 1e-9)
+						require.InDelta(t, st.squares, cached.moves[i][midx].squares, 1e-9)
+					}
+				}
+				for _, tup := range n.childs {
+					check(tup.child)
+				}
+			}
+			check(root)
+		})
+	}
+}
+
+// BenchmarkOnce reports the iterations per second of OptsV5 on fixtures. Caching per move
+// stats instead of recomputing them during selection changed the median of 5 runs from:
+//
+//	022: 28.8k it/s, 023: 37.4k it/s, 027: 177k it/s
+//
+// to the following, 022 is dominated by heuristic leaf evaluation:
+//
+//	022: 28.7k it/s, 023: 49.5k it/s, 027: 233k it/s
+func BenchmarkOnce(b *testing.B) {
+	for _, file := range []string{"../testdata/input-022.json", "../testdata/input-023.json", "../testdata/input-027.json"} {
+		board, rootIdx := fileToBoard(b, file)
+		b.Run(file[len(file)-8:len(file)-5], func(b *testing.B) {
+			root := NewRoot(&rules.StandardRuleset{}, board, rootIdx)
+			t0 := time.Now()
+			for i := 0; i < b.N; i++ {
+				if err := Once(root, &OptsV5); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N)/time.Since(t0).Seconds(), "it/s")
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package mcts
+
+import (
+	"fmt"
+
+	"github.com/BattlesnakeOfficial/rules"
+
+	"github.com/corverroos/bsnake/board"
+	"github.com/corverroos/bsnake/heur"
+)
+
+// Playout provides the moves of snakes during play-outs.
+type Playout interface {
+	// Move returns the move of the snake.
+	Move(b *rules.BoardState, snakeIdx int, o *Opts) string
+}
+
+// Playouts are the play-out policies by name, see Opts.Playout.
+var Playouts = map[string]Playout{
+	"random":   RandomPlayout{},
+	"greedy":   GreedyPlayout{},
+	"avoidh2h": AvoidH2HPlayout{},
+	"food":     FoodPlayout{},
+	"model":    ModelPlayout{},
+}
+
+// RandomPlayout selects a random rational move.
+type RandomPlayout struct{}
+
+func (RandomPlayout) Move(b *rules.BoardState, snakeIdx int, o *Opts) string {
+	return randMove(b, snakeIdx, o, func(string) bool { return true })
+}
+
+// GreedyPlayout selects the move with the best one-ply heuristic score with probability
+// 1-PlayoutEpsilon, otherwise a random rational move.
+type GreedyPlayout struct{}
+
+func (GreedyPlayout) Move(b *rules.BoardState, snakeIdx int, o *Opts) string {
+	eps := o.PlayoutEpsilon
+	if eps == 0 {
+		eps = 0.1
+	}
+
+	if o.float64() < eps {
+		return RandomPlayout{}.Move(b, snakeIdx, o)
+	}
+
+	return greedyMove(b, snakeIdx, o)
+}
+
+// AvoidH2HPlayout selects a random rational move that doesn't risk a loosing head-to-head
+// if possible.
+type AvoidH2HPlayout struct{}
+
+func (AvoidH2HPlayout) Move(b *rules.BoardState, snakeIdx int, o *Opts) string {
+	return randMove(b, snakeIdx, o, func(move string) bool {
+		return !board.IsLoosingH2H(b, snakeIdx, move)
+	})
+}
+
+// FoodPlayout selects a rational move towards the closest food if the snake's health is at
+// or below PlayoutHunger, otherwise a random rational move.
+type FoodPlayout struct{}
+
+func (FoodPlayout) Move(b *rules.BoardState, snakeIdx int, o *Opts) string {
+	hunger := o.PlayoutHunger
+	if hunger == 0 {
+		hunger = 30
+	}
+
+	s := b.Snakes[snakeIdx]
+	if s.Health > hunger || len(b.Food) == 0 {
+		return RandomPlayout{}.Move(b, snakeIdx, o)
+	}
+
+	food := b.Food[0]
+	for _, f := range b.Food[1:] {
+		if board.Distance(s.Body[0], f) < board.Distance(s.Body[0], food) {
+			food = f
+		}
+	}
+
+	closer := board.Distance(s.Body[0], food)
+	return randMove(b, snakeIdx, o, func(move string) bool {
+		return board.Distance(board.MovePoint(s.Body[0], move), food) < closer
+	})
+}
+
+// randMove returns a random rational move preferring those that are ok. If no
+// move is rational, a random move is returned.
+func randMove(b *rules.BoardState, snakeIdx int, o *Opts, ok func(string) bool) string {
+	moves := board.RandMoves(o.Rand)
+
+	var fallback string
+	for _, move := range moves {
+		if !board.IsRationalMove(b, snakeIdx, move) {
+			continue
+		}
+		if ok(move) {
+			return move
+		}
+		if fallback == "" {
+			fallback = move
+		}
+	}
+
+	if fallback != "" {
+		return fallback
+	}
+
+	return moves[len(moves)-1]
+}
+
+// greedyMove returns the rational move with the best one-ply heuristic score.
+func greedyMove(b *rules.BoardState, snakeIdx int, o *Opts) string {
+	f := o.HeurFactors
+	if f == nil {
+		f = OptsV5.HeurFactors
+	}
+
+	var (
+		res string
+		max float64
+	)
+	for _, move := range board.Moves {
+		if !board.IsRationalMove(b, snakeIdx, move) {
+			continue
+		}
+		score := heur.Calc(f, board.MoveSnake(b, snakeIdx, move), snakeIdx, o.hazards)[snakeIdx]
+		if res == "" || score > max {
+			res = move
+			max = score
+		}
+	}
+
+	if res == "" {
+		return RandomPlayout{}.Move(b, snakeIdx, o)
+	}
+
+	return res
+}
+
+// ModelPlayout samples moves from the Opts.Opponents move model, falling back to random
+// if no model is set.
+type ModelPlayout struct{}
+
+func (ModelPlayout) Move(b *rules.BoardState, snakeIdx int, o *Opts) string {
+	if o.Opponents == nil {
+		return RandomPlayout{}.Move(b, snakeIdx, o)
+	}
+
+	priors := o.Opponents.Priors(b, snakeIdx, o.hazards)
+
+	r := o.float64()
+	for midx, p := range priors {
+		r -= p
+		if r < 0 && p > 0 {
+			return board.Moves[midx]
+		}
+	}
+
+	return RandomPlayout{}.Move(b, snakeIdx, o)
+}
+
+// playoutPolicy returns the play-out policy of the snake; PlayoutOpp for opponents if
+// set, otherwise Playout, defaulting to random.
+func (o *Opts) playoutPolicy(snakeIdx, rootIdx int) Playout {
+	name := o.Playout
+	if snakeIdx != rootIdx && o.PlayoutOpp != "" {
+		name = o.PlayoutOpp
+	}
+	if name == "" {
+		name = "random"
+	}
+
+	p, ok := Playouts[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown playout policy: %s", name))
+	}
+
+	return p
+}
@@ -0,0 +1,121 @@
+package mcts
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/corverroos/bsnake/board"
+	"github.com/corverroos/bsnake/boardtxt"
+)
+
+func TestPlayouts(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Playout string
+		Opts    Opts
+		Ok      func(b *rules.BoardState, snakeIdx int, move string) bool
+	}{
+		{
+			Name:    "../testdata/input-022.json",
+			Playout: "random",
+			Ok: func(b *rules.BoardState, snakeIdx int, move string) bool {
+				return board.IsRationalMove(b, snakeIdx, move)
+			},
+		},
+		{
+			Name:    "../testdata/input-027.json",
+			Playout: "avoidh2h",
+			Ok: func(b *rules.BoardState, snakeIdx int, move string) bool {
+				return board.IsRationalMove(b, snakeIdx, move) && !board.IsLoosingH2H(b, snakeIdx, move)
+			},
+		},
+		{
+			Name:    "../testdata/input-010.json", // Health 7
+			Playout: "food",
+			Ok: func(b *rules.BoardState, snakeIdx int, move string) bool {
+				head := b.Snakes[snakeIdx].Body[0]
+				var min int32 = -1
+				for _, f := range b.Food {
+					if d := board.Distance(head, f); min == -1 || d < min {
+						min = d
+					}
+				}
+				next := board.MovePoint(head, move)
+				for _, f := range b.Food {
+					if board.Distance(head, f) == min && board.Distance(next, f) < min {
+						return true
+					}
+				}
+				return false
+			},
+		},
+		{
+			Name:    "../testdata/input-020.json",
+			Playout: "greedy",
+			Opts:    Opts{PlayoutEpsilon: 0.0001},
+			Ok: func(b *rules.BoardState, snakeIdx int, move string) bool {
+				return move == greedyMove(b, snakeIdx, &Opts{})
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Playout, func(t *testing.T) {
+			b, rootIdx := fileToBoard(t, test.Name)
+			o := test.Opts
+			o.Playout = test.Playout
+
+			for i := 0; i < 100; i++ {
+				rand.Seed(int64(i))
+				move := o.playoutPolicy(rootIdx, rootIdx).Move(b, rootIdx, &o)
+				require.True(t, test.Ok(b, rootIdx, move), "seed=%d move=%s", i, move)
+			}
+		})
+	}
+}
+
+func TestPlayoutOpts(t *testing.T) {
+	o := &Opts{Playout: "food", PlayoutOpp: "greedy"}
+	require.Equal(t, FoodPlayout{}, o.playoutPolicy(0, 0))
+	require.Equal(t, GreedyPlayout{}, o.playoutPolicy(1, 0))
+
+	o = &Opts{}
+	require.Equal(t, RandomPlayout{}, o.playoutPolicy(1, 0))
+
+	o = &Opts{Playout: "unknown"}
+	require.Panics(t, func() { o.playoutPolicy(0, 0) })
+
+	// GreedyProb without GreedyHeur uses the greedy policy.
+	b, rootIdx := fileToBoard(t, "../testdata/input-022.json")
+	n0 := NewRoot(&rules.StandardRuleset{}, b, rootIdx)
+	o = &Opts{MaxPlayout: 10, GreedyProb: 1}
+	_, err := playoutRandomRational(n0, n0, o, nil)
+	jtest.RequireNil(t, err)
+}
+
+func TestRandMove(t *testing.T) {
+	g, err := boardtxt.Parse(`
+...
+Y..
+yy.
+`)
+	jtest.RequireNil(t, err)
+
+	// Left into the wall is ok but irrational, rational moves are preferred.
+	ok := func(move string) bool { return move == "left" }
+
+	o := &Opts{Rand: rand.New(rand.NewSource(0))}
+	for i := 0; i < 100; i++ {
+		move := randMove(g.Board, g.You, o, ok)
+		require.Contains(t, []string{"up", "right"}, move)
+	}
+
+	// A random move if none is rational.
+	g, err = boardtxt.Parse("Y\n")
+	jtest.RequireNil(t, err)
+	require.Contains(t, board.Moves, randMove(g.Board, g.You, o, ok))
+}
@@ -0,0 +1,145 @@
+package mcts
+
+import (
+	"math"
+
+	"github.com/BattlesnakeOfficial/rules"
+
+	"github.com/corverroos/bsnake/board"
+	"github.com/corverroos/bsnake/heur"
+)
+
+// Bandit defines the per snake move selection policy used during selection.
+type Bandit int
+
+const (
+	// BanditUCB1 selects moves using UCB1 or UCB1-Tuned with optional progressive bias.
+	BanditUCB1 Bandit = 0
+
+	// BanditPUCT selects moves using PUCT with move priors from Opts.Policy.
+	BanditPUCT Bandit = 1
+)
+
+// puctC returns the prior exploration factor of BanditPUCT. Zero would reduce PUCT to
+// greedy selection of the mean score.
+func (o *Opts) puctC() float64 {
+	if o.PUCT_C == 0 {
+		return 1.5
+	}
+	return o.PUCT_C
+}
+
+// Policy provides move prior probabilities for PUCT selection.
+type Policy interface {
+	// Priors returns the prior probability of each move in board.Moves for the snake.
+	Priors(b *rules.BoardState, snakeIdx int, hazards map[rules.Point]bool) []float64
+}
+
+// UniformPolicy assigns equal priors to all rational moves.
+type UniformPolicy struct{}
+
+func (UniformPolicy) Priors(b *rules.BoardState, snakeIdx int, _ map[rules.Point]bool) []float64 {
+	res := make([]float64, len(board.Moves))
+	for midx, move := range board.Moves {
+		if board.IsRationalMove(b, snakeIdx, move) {
+			res[midx] = 1
+		}
+	}
+	return normalizePriors(res)
+}
+
+// HeurPolicy assigns priors as the softmax of the one-ply heuristic scores
+// of each rational move.
+type HeurPolicy struct {
+	Factors *heur.Factors
+	Temp    float64 // Softmax temperature, defaults to 0.1.
+}
+
+func (p HeurPolicy) Priors(b *rules.BoardState, snakeIdx int, hazards map[rules.Point]bool) []float64 {
+	return softmaxPriors(b, snakeIdx, p.Temp, func(next *rules.BoardState) float64 {
+		return heur.Calc(p.Factors, next, snakeIdx, hazards)[snakeIdx]
+	})
+}
+
+// AreaPolicy assigns priors as the softmax of the fraction of the board
+// controlled after each rational move.
+type AreaPolicy struct {
+	Temp float64 // Softmax temperature, defaults to 0.1.
+}
+
+func (p AreaPolicy) Priors(b *rules.BoardState, snakeIdx int, hazards map[rules.Point]bool) []float64 {
+	return softmaxPriors(b, snakeIdx, p.Temp, func(next *rules.BoardState) float64 {
+		control, _ := heur.Flood(next, snakeIdx, hazards)
+		return control[snakeIdx] / float64(b.Width*b.Height)
+	})
+}
+
+// softmaxPriors returns the softmax of the scores of the boards after each rational move of the snake.
+func softmaxPriors(b *rules.BoardState, snakeIdx int, temp float64, score func(*rules.BoardState) float64) []float64 {
+	if temp == 0 {
+		temp = 0.1
+	}
+
+	var (
+		scores = make([]float64, len(board.Moves))
+		ok     = make([]bool, len(board.Moves))
+		max    = math.Inf(-1)
+	)
+
+	for midx, move := range board.Moves {
+		if !board.IsRationalMove(b, snakeIdx, move) {
+			continue
+		}
+		scores[midx] = score(board.MoveSnake(b, snakeIdx, move))
+		ok[midx] = true
+		if scores[midx] > max {
+			max = scores[midx]
+		}
+	}
+
+	res := make([]float64, len(board.Moves))
+	for midx := range board.Moves {
+		if ok[midx] {
+			res[midx] = math.Exp((scores[midx] - max) / temp)
+		}
+	}
+
+	return normalizePriors(res)
+}
+
+// normalizePriors normalises the priors to sum to 1, or returns uniform priors if they sum to 0.
+func normalizePriors(priors []float64) []float64 {
+	var total float64
+	for _, p := range priors {
+		total += p
+	}
+
+	for i := range priors {
+		if total == 0 {
+			priors[i] = 1 / float64(len(priors))
+		} else {
+			priors[i] /= total
+		}
+	}
+
+	return priors
+}
+
+// Priors returns the cached move priors of the snake at n.
+func (n *node) Priors(o *Opts, snakeIdx int) []float64 {
+	if n.priors == nil {
+		n.priors = make([][]float64, len(n.idsByIdx))
+	}
+
+	if n.priors[snakeIdx] == nil {
+		var p Policy = UniformPolicy{}
+		if o.Opponents != nil && snakeIdx != n.rootIdx {
+			p = o.Opponents
+		} else if o.Policy != nil {
+			p = o.Policy
+		}
+		n.priors[snakeIdx] = p.Priors(n.board, snakeIdx, o.hazards)
+	}
+
+	return n.priors[snakeIdx]
+}
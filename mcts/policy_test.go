@@ -0,0 +1,192 @@
+package mcts
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/corverroos/bsnake/board"
+	"github.com/corverroos/bsnake/boardtxt"
+)
+
+func TestPolicies(t *testing.T) {
+	policies := map[string]Policy{
+		"uniform": UniformPolicy{},
+		"heur":    HeurPolicy{Factors: OptsV5.HeurFactors},
+		"area":    AreaPolicy{},
+	}
+
+	tests := []struct {
+		Name string
+		Zero []string
+		Max  map[string]string
+	}{
+		{
+			Name: "../testdata/input-002.json",
+			Zero: []string{"up", "down"},
+		},
+		{
+			Name: "../testdata/input-020.json",
+			Zero: []string{"right"},
+			Max:  map[string]string{"heur": "left", "area": "left"},
+		},
+		{
+			Name: "../testdata/input-031.json",
+			Zero: []string{"up", "down"},
+			Max:  map[string]string{"heur": "right", "area": "right"},
+		},
+	}
+
+	for _, test := range tests {
+		for name, policy := range policies {
+			t.Run(test.Name+"_"+name, func(t *testing.T) {
+				b, rootIdx := fileToBoard(t, test.Name)
+				priors := policy.Priors(b, rootIdx, nil)
+				fmt.Printf("priors=%v\n", priors)
+
+				var total float64
+				max := -1
+				for midx, p := range priors {
+					total += p
+					if max == -1 || p > priors[max] {
+						max = midx
+					}
+				}
+				require.InDelta(t, 1.0, total, 1e-9)
+
+				if exp, ok := test.Max[name]; ok {
+					require.Equal(t, exp, board.Moves[max])
+				}
+
+				for midx, move := range board.Moves {
+					for _, zero := range test.Zero {
+						if zero == move {
+							require.Zero(t, priors[midx])
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestPUCT(t *testing.T) {
+	tests := []struct {
+		Name string
+		Exp  string
+	}{
+		{
+			Name: "../testdata/input-001.json",
+			Exp:  "down",
+		},
+		{
+			Name: "../testdata/input-020.json",
+			Exp:  "down",
+		},
+		{
+			Name: "../testdata/input-023.json",
+			Exp:  "right",
+		},
+		{
+			Name: "../testdata/input-031.json",
+			Exp:  "right",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			b, rootIdx := fileToBoard(t, test.Name)
+
+			var r rules.Ruleset = &rules.StandardRuleset{}
+			if len(b.Snakes) == 1 {
+				r = &rules.SoloRuleset{}
+			}
+
+			o := OptsV5
+			o.Bandit = BanditPUCT
+			o.PUCT_C = 2
+			o.Policy = AreaPolicy{}
+
+			root := NewRoot(r, b, rootIdx)
+			for i := 0; i < 5000; i++ {
+				rand.Seed(int64(i))
+				jtest.RequireNil(t, Once(root, &o))
+			}
+
+			fmt.Printf("RobustMoves=%v priors=%v\n", root.RobustMoves(rootIdx), root.Priors(&o, rootIdx))
+			require.Equal(t, test.Exp, root.RobustSafeMove(rootIdx))
+		})
+	}
+}
+
+func TestPUCTDefault(t *testing.T) {
+	require.Equal(t, 1.5, (&Opts{}).puctC())
+	require.Equal(t, 2.0, (&Opts{PUCT_C: 2}).puctC())
+}
+
+func TestPolicyJSON(t *testing.T) {
+	o := OptsV5
+	o.Bandit = BanditPUCT
+	o.Policy = fixedPolicy{1, 0, 0, 0}
+
+	b, err := json.Marshal(o)
+	jtest.RequireNil(t, err)
+
+	var res Opts
+	jtest.RequireNil(t, json.Unmarshal(b, &res))
+	require.Nil(t, res.Policy)
+	require.Equal(t, BanditPUCT, res.Bandit)
+}
+
+func TestOpponentModel(t *testing.T) {
+	g, err := boardtxt.Parse(`
+snake: Y health=2
+.......
+.......
+.......
+...Y*Ss
+...y..s
+...y..s
+.......
+`)
+	jtest.RequireNil(t, err)
+
+	// rightVisits returns the fraction of the root snake's visits moving right to the only
+	// food in reach, next to the longer opponent's head, given the opponent's modelled move.
+	rightVisits := func(opp string) float64 {
+		priors := make(fixedPolicy, len(board.Moves))
+		for midx, move := range board.Moves {
+			if move == opp {
+				priors[midx] = 1
+			}
+		}
+
+		o := OptsV5O
+		o.Opponents = priors
+		o.Rand = rand.New(rand.NewSource(1))
+
+		root := NewRoot(&rules.StandardRuleset{}, g.Board, g.You)
+		for i := 0; i < 2000; i++ {
+			jtest.RequireNil(t, Once(root, &o))
+		}
+
+		var total, right float64
+		for midx, st := range root.getStats().moves[g.You] {
+			total += st.n
+			if board.Moves[midx] == "right" {
+				right = st.n
+			}
+		}
+		return right / total
+	}
+
+	// The root snake starves unless it eats, it favours the food if the opponent isn't
+	// modelled to contest it.
+	left, up := rightVisits("left"), rightVisits("up")
+	require.Less(t, left, up-0.1, "left=%.2f up=%.2f", left, up)
+}
@@ -0,0 +1,229 @@
+package mcts
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/BattlesnakeOfficial/rules"
+)
+
+// Ponder continues searching the subtree of the chosen move while the opponents
+// think and reuses it for the next turn's search if the next board matches.
+// Searches don't spawn food, so the subtree is missed, not reused, whenever food
+// spawned in the actual game, see Reuses.
+// A Ponder is per game and per snake.
+type Ponder struct {
+	mu      sync.Mutex
+	root    *node
+	hazards []rules.Point
+	cancel  context.CancelFunc
+	done    chan struct{}
+	hits    int
+	misses  int
+}
+
+// SelectMove returns the move of the root snake like SelectMove, searching from the
+// pondered subtree if it matches the board, and then starts pondering the chosen move.
+func (p *Ponder) SelectMove(ctx context.Context, board *rules.BoardState, hazards []rules.Point, rootIDx int, o *Opts) (string, error) {
+	move, _, err := p.SelectMoveStop(ctx, board, hazards, rootIDx, o)
+	return move, err
+}
+
+// SelectMoveStop returns the move like SelectMove and the early stop rule of the search,
+// see SelectMoveStop.
+func (p *Ponder) SelectMoveStop(ctx context.Context, board *rules.BoardState, hazards []rules.Point, rootIDx int, o *Opts) (string, string, error) {
+	t0 := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stop()
+
+	o.hazards = make(map[rules.Point]bool)
+	for _, hazard := range hazards {
+		o.hazards[hazard] = true
+	}
+
+	pondered := p.root != nil
+	root, ok := p.take(board, hazards, rootIDx)
+	if ok {
+		p.hits++
+		o.Logd("ponder subtree reused, visits=%.0f", root.n)
+	} else {
+		if pondered {
+			p.misses++
+			o.Logd("ponder subtree missed")
+		}
+		root = NewRoot(newRuleset(board, hazards), board, rootIDx)
+	}
+
+	move, stop, err := search(t0, budget(ctx, t0), root, rootIDx, o)
+	if err != nil {
+		return "", "", err
+	}
+
+	p.start(root, rootIDx, move, hazards, o)
+
+	return move, stop, nil
+}
+
+// Reuses returns the number of turns the pondered subtree was reused and missed,
+// e.g. due to food spawning.
+func (p *Ponder) Reuses() (hits int, misses int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.hits, p.misses
+}
+
+// Stop stops pondering and discards the pondered subtree.
+func (p *Ponder) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stop()
+	if p.root != nil {
+		p.root.release()
+		p.root = nil
+	}
+}
+
+// stop cancels pondering and waits for it to return.
+func (p *Ponder) stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+	p.cancel = nil
+}
+
+// start prunes the root to the subtree of the root snake's move and searches it in the
+// background until stopped, PonderTime elapses or the tree exceeds PonderNodes.
+func (p *Ponder) start(root *node, rootIDx int, move string, hazards []rules.Point, o *Opts) {
+	var childs []tuple
+	for _, tup := range root.childs {
+		if tup.edge.Is(rootIDx, move) {
+			childs = append(childs, tup)
+		} else {
+			tup.child.release()
+		}
+	}
+	root.childs = childs
+	root.stats = nil
+	root.tree.nodes = root.Size()
+	if len(childs) == 0 {
+		root.release()
+		return
+	}
+
+	timeout := o.PonderTime
+	if timeout == 0 {
+		timeout = time.Second
+	}
+	maxNodes := o.PonderNodes
+	if maxNodes == 0 {
+		maxNodes = 500000
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	done := make(chan struct{})
+
+	p.root = root
+	p.hazards = hazards
+	p.cancel = cancel
+	p.done = done
+
+	po := *o
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			if root.tree.nodes > maxNodes {
+				return
+			}
+			if err := Once(root, &po); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// take returns the pondered child matching the board as a new root.
+func (p *Ponder) take(board *rules.BoardState, hazards []rules.Point, rootIDx int) (*node, bool) {
+	root := p.root
+	p.root = nil
+
+	if root == nil {
+		return nil, false
+	} else if root.rootIdx != rootIDx || !pointsEqual(p.hazards, hazards) {
+		root.release()
+		return nil, false
+	}
+
+	var res *node
+	for i, tup := range root.childs {
+		if res == nil && boardsMatch(tup.child.board, board) {
+			res = tup.child
+			res.parent = nil
+			root.childs[i] = tuple{}
+			continue
+		}
+		tup.child.release()
+		root.childs[i] = tuple{}
+	}
+	root.childs = root.childs[:0]
+	root.release()
+
+	if res == nil {
+		return nil, false
+	}
+
+	res.tree.nodes = res.Size()
+
+	return res, true
+}
+
+// boardsMatch returns true if the searched board a matches the actual board b,
+// including the food which never spawns in searches.
+func boardsMatch(a, b *rules.BoardState) bool {
+	if a.Width != b.Width || a.Height != b.Height {
+		return false
+	} else if len(a.Snakes) != len(b.Snakes) || len(a.Food) != len(b.Food) {
+		return false
+	}
+
+	for i := range a.Snakes {
+		sa, sb := a.Snakes[i], b.Snakes[i]
+		if sa.ID != sb.ID || sa.EliminatedCause != "" || sa.Health != sb.Health {
+			return false
+		}
+		if !pointsEqual(sa.Body, sb.Body) {
+			return false
+		}
+	}
+
+	food := make(map[rules.Point]bool)
+	for _, f := range a.Food {
+		food[f] = true
+	}
+	for _, f := range b.Food {
+		if !food[f] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func pointsEqual(a, b []rules.Point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
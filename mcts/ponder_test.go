@@ -0,0 +1,133 @@
+package mcts
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPonder(t *testing.T) {
+	b, rootIdx := fileToBoard(t, "../testdata/input-023.json")
+
+	var logs []string
+	o := OptsV5
+	o.logd = func(s string, i ...interface{}) {
+		logs = append(logs, fmt.Sprintf(s, i...))
+	}
+
+	var p Ponder
+	move, err := p.SelectMove(context.Background(), b, nil, rootIdx, &o)
+	jtest.RequireNil(t, err)
+
+	p.mu.Lock()
+	root := p.root
+	p.mu.Unlock()
+	require.NotNil(t, root)
+
+	// Wait for some pondering
+	time.Sleep(time.Millisecond * 50)
+	p.mu.Lock()
+	p.stop()
+	p.mu.Unlock()
+
+	// Only the chosen move remains
+	var next *node
+	for _, tup := range root.childs {
+		require.True(t, tup.edge.Is(rootIdx, move))
+		if next == nil || next.n < tup.child.n {
+			next = tup.child
+		}
+	}
+	visits := next.n
+
+	// Next turn with matching board reuses the subtree
+	logs = nil
+	_, err = p.SelectMove(context.Background(), copyBoard(next.board), nil, rootIdx, &o)
+	jtest.RequireNil(t, err)
+	require.Nil(t, next.parent)
+
+	p.Stop()
+	require.Nil(t, p.root)
+	require.Contains(t, logs, fmt.Sprintf("ponder subtree reused, visits=%.0f", visits))
+	require.Nil(t, p.cancel)
+
+	// Spawned food misses the pondered subtree.
+	_, err = p.SelectMove(context.Background(), b, nil, rootIdx, &o)
+	jtest.RequireNil(t, err)
+
+	p.mu.Lock()
+	p.stop()
+	require.NotNil(t, p.root)
+	for _, tup := range p.root.childs {
+		next = tup.child
+	}
+	p.mu.Unlock()
+
+	spawned := copyBoard(next.board)
+	spawned.Food = append(spawned.Food, emptyPoint(t, spawned))
+
+	logs = nil
+	_, err = p.SelectMove(context.Background(), spawned, nil, rootIdx, &o)
+	jtest.RequireNil(t, err)
+	p.Stop()
+
+	require.Contains(t, logs, "ponder subtree missed")
+	hits, misses := p.Reuses()
+	require.Equal(t, 1, hits)
+	require.Equal(t, 1, misses)
+}
+
+// emptyPoint returns a point of the board without snakes or food.
+func emptyPoint(t *testing.T, b *rules.BoardState) rules.Point {
+	used := make(map[rules.Point]bool)
+	for _, f := range b.Food {
+		used[f] = true
+	}
+	for _, s := range b.Snakes {
+		for _, p := range s.Body {
+			used[p] = true
+		}
+	}
+	for x := int32(0); x < b.Width; x++ {
+		for y := int32(0); y < b.Height; y++ {
+			if p := (rules.Point{X: x, Y: y}); !used[p] {
+				return p
+			}
+		}
+	}
+	t.Fatal("no empty point")
+	return rules.Point{}
+}
+
+func TestBoardsMatch(t *testing.T) {
+	b, _ := fileToBoard(t, "../testdata/input-023.json")
+	require.True(t, boardsMatch(b, copyBoard(b)))
+
+	c := copyBoard(b)
+	c.Food = c.Food[1:]
+	require.False(t, boardsMatch(b, c))
+
+	c = copyBoard(b)
+	c.Snakes[0].Health--
+	require.False(t, boardsMatch(b, c))
+
+	c = copyBoard(b)
+	c.Food[0], c.Food[1] = c.Food[1], c.Food[0]
+	require.True(t, boardsMatch(b, c))
+}
+
+func copyBoard(b *rules.BoardState) *rules.BoardState {
+	res := *b
+	res.Food = append([]rules.Point(nil), b.Food...)
+	res.Snakes = make([]rules.Snake, len(b.Snakes))
+	for i, s := range b.Snakes {
+		s.Body = append([]rules.Point(nil), s.Body...)
+		res.Snakes[i] = s
+	}
+	return &res
+}
@@ -0,0 +1,73 @@
+package mcts
+
+import "sync"
+
+// tree is shared by all nodes of a search tree.
+type tree struct {
+	nodes int // Number of nodes in the tree.
+}
+
+// nodePool reuses released nodes and their slices across searches. Nodes are only
+// released when a search ends or pondering advances the root, see Ponder. Idle pooled
+// nodes are freed by the garbage collector like any sync.Pool.
+var nodePool = sync.Pool{
+	New: func() interface{} {
+		return new(node)
+	},
+}
+
+// allocNode returns a zeroed node from the pool for l snakes.
+func allocNode(l int) *node {
+	n := nodePool.Get().(*node)
+
+	childs := n.childs[:0]
+	if childs == nil {
+		childs = make([]tuple, 0, 64)
+	}
+
+	*n = node{
+		childs:       childs,
+		totals:       zeroFloats(n.totals, l),
+		totalSquares: zeroFloats(n.totalSquares, l),
+		heurTotals:   zeroFloats(n.heurTotals, l),
+	}
+
+	return n
+}
+
+// release returns the node and all its descendants to the pool. The nodes may not
+// be used afterwards.
+func (n *node) release() {
+	for i, tup := range n.childs {
+		tup.child.release()
+		n.childs[i] = tuple{}
+	}
+
+	*n = node{
+		childs:       n.childs[:0],
+		totals:       n.totals,
+		totalSquares: n.totalSquares,
+		heurTotals:   n.heurTotals,
+	}
+
+	nodePool.Put(n)
+}
+
+// treeFull returns true if the tree has reached MaxNodes. A full tree is only capped:
+// nodes are neither pruned nor recycled during the search, leaves are evaluated
+// without expansion instead.
+func (n *node) treeFull(o *Opts) bool {
+	return o.MaxNodes > 0 && n.tree.nodes >= o.MaxNodes
+}
+
+// zeroFloats returns s resized to l and zeroed, allocating if s is too small.
+func zeroFloats(s []float64, l int) []float64 {
+	if cap(s) < l {
+		return make([]float64, l)
+	}
+	s = s[:l]
+	for i := range s {
+		s[i] = 0
+	}
+	return s
+}
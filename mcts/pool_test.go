@@ -0,0 +1,88 @@
+package mcts
+
+import (
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxNodes(t *testing.T) {
+	b, rootIdx := fileToBoard(t, "../testdata/input-022.json")
+
+	o := OptsV5
+	o.MaxNodes = 1000
+	o.Rand = rand.New(rand.NewSource(0))
+
+	root := NewRoot(&rules.StandardRuleset{}, b, rootIdx)
+	for i := 0; i < 5000; i++ {
+		jtest.RequireNil(t, Once(root, &o))
+		require.Equal(t, float64(i+2), root.n)
+	}
+
+	require.Equal(t, root.Size(), root.tree.nodes)
+	require.True(t, root.tree.nodes >= o.MaxNodes)
+	require.True(t, root.tree.nodes < o.MaxNodes+64) // At most one expansion more
+}
+
+func TestRelease(t *testing.T) {
+	b, rootIdx := fileToBoard(t, "../testdata/input-022.json")
+
+	root := NewRoot(&rules.StandardRuleset{}, b, rootIdx)
+	for i := 0; i < 100; i++ {
+		jtest.RequireNil(t, Once(root, &OptsV5))
+	}
+	root.release()
+
+	for i := 0; i < 100; i++ {
+		n := allocNode(3)
+		require.Nil(t, n.parent)
+		require.Nil(t, n.board)
+		require.Empty(t, n.childs)
+		require.Equal(t, []float64{0, 0, 0}, n.totals)
+		require.Equal(t, []float64{0, 0, 0}, n.totalSquares)
+		require.Zero(t, n.n)
+	}
+}
+
+// BenchmarkSearch reports memory and GC pauses of 2000 iterations with and without
+// returning the tree to the node pool:
+//
+//	BenchmarkSearch/gc        49  64.7ms/op  70k gc-pause-ns/op  32.9MB/op  268k allocs/op
+//	BenchmarkSearch/release   70  63.7ms/op  48k gc-pause-ns/op  23.3MB/op  236k allocs/op
+func BenchmarkSearch(b *testing.B) {
+	board, rootIdx := fileToBoard(b, "../testdata/input-022.json")
+
+	for _, release := range []bool{false, true} {
+		name := "gc"
+		if release {
+			name = "release"
+		}
+		b.Run(name, func(b *testing.B) {
+			var m0, m1 runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&m0)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				root := NewRoot(&rules.StandardRuleset{}, board, rootIdx)
+				for j := 0; j < 2000; j++ {
+					if err := Once(root, &OptsV5); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if release {
+					root.release()
+				}
+			}
+
+			b.StopTimer()
+			runtime.ReadMemStats(&m1)
+			b.ReportMetric(float64(m1.PauseTotalNs-m0.PauseTotalNs)/float64(b.N), "gc-pause-ns/op")
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package mcts
+
+import (
+	"math"
+
+	"github.com/corverroos/bsnake/board"
+)
+
+// raveStats are the all-moves-as-first statistics of a snake's moves in board.Moves order.
+type raveStats struct {
+	n      [4]float64
+	totals [4]float64
+}
+
+// raveMoves are the moves played by each snake during an iteration, indexed by board.Moves.
+type raveMoves [][4]bool
+
+func newRaveMoves(l int) raveMoves {
+	return make(raveMoves, l)
+}
+
+// add marks the move as played by the snake. It is a noop if m is nil.
+func (m raveMoves) add(snakeIdx int, move string) {
+	if m == nil {
+		return
+	}
+	for midx, mv := range board.Moves {
+		if mv == move {
+			m[snakeIdx][midx] = true
+			return
+		}
+	}
+}
+
+// addEdge marks the moves of all snakes in the edge as played.
+func (m raveMoves) addEdge(e edge, l int) {
+	for i := 0; i < l; i++ {
+		for midx, move := range board.Moves {
+			if e.Is(i, move) {
+				m[i][midx] = true
+				break
+			}
+		}
+	}
+}
+
+// updateRave updates the AMAF statistics of n with the moves played at or below n during
+// the iteration and then adds the edge to n to the played moves. It is a noop if seen is nil.
+func (n *node) updateRave(totals []float64, seen raveMoves) {
+	if seen == nil {
+		return
+	}
+
+	if n.rave == nil {
+		n.rave = make([]raveStats, len(totals))
+	}
+
+	for i, t := range totals {
+		for midx := range board.Moves {
+			if !seen[i][midx] {
+				continue
+			}
+			n.rave[i].n[midx]++
+			n.rave[i].totals[midx] += t
+		}
+	}
+
+	if n.parent != nil {
+		seen.addEdge(n.edge, len(totals))
+	}
+}
+
+// raveMean returns the average score of the snake's move blended with its AMAF average score
+// using the hand-selected schedule beta = sqrt(k/(3n+k)) from Gelly and Silver, "Monte-Carlo tree
+// search and rapid action value estimation in computer Go" (2011).
+func (n *node) raveMean(o *Opts, snakeIdx, midx int, sumN, mean float64) float64 {
+	if n.rave == nil || n.rave[snakeIdx].n[midx] == 0 {
+		return mean
+	}
+
+	k := o.RaveK
+	if k == 0 {
+		k = 500
+	}
+
+	beta := math.Sqrt(k / (3*sumN + k))
+	amaf := n.rave[snakeIdx].totals[midx] / n.rave[snakeIdx].n[midx]
+
+	return (1-beta)*mean + beta*amaf
+}
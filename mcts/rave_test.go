@@ -0,0 +1,85 @@
+package mcts
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+// raveRoot returns the root after the iterations with or without RAVE from a local
+// random source.
+func raveRoot(t *testing.T, file string, iters int, rave bool, seed int64) (*node, int) {
+	b, rootIdx := fileToBoard(t, file)
+
+	var r rules.Ruleset = &rules.StandardRuleset{}
+	if len(b.Snakes) == 1 {
+		r = &rules.SoloRuleset{}
+	}
+
+	o := OptsV5
+	o.AvoidLH2H = true
+	o.Rave = rave
+	o.Rand = rand.New(rand.NewSource(seed))
+
+	root := NewRoot(r, b, rootIdx)
+	for i := 0; i < iters; i++ {
+		jtest.RequireNil(t, Once(root, &o))
+	}
+	return root, rootIdx
+}
+
+// TestRave compares the robust moves of 5000 iterations with and without RAVE. RAVE
+// differs in 025, 027 and 031 where the moves are close.
+func TestRave(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Exp     string
+		ExpRave string
+	}{
+		{Name: "../testdata/input-001.json", Exp: "down"},
+		{Name: "../testdata/input-017.json", Exp: "left"},
+		{Name: "../testdata/input-020.json", Exp: "down"},
+		{Name: "../testdata/input-021.json", Exp: "left"},
+		{Name: "../testdata/input-022.json", Exp: "right"},
+		{Name: "../testdata/input-023.json", Exp: "right"},
+		{Name: "../testdata/input-024.json", Exp: "left"},
+		{Name: "../testdata/input-025.json", Exp: "up", ExpRave: "right"},
+		{Name: "../testdata/input-027.json", Exp: "down", ExpRave: "right"},
+		{Name: "../testdata/input-028.json", Exp: "up"},
+		{Name: "../testdata/input-030.json", Exp: "up"},
+		{Name: "../testdata/input-031.json", Exp: "right", ExpRave: "left"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			plain, rootIdx := raveRoot(t, test.Name, 5000, false, 1)
+			require.Equal(t, test.Exp, plain.RobustSafeMove(rootIdx))
+
+			exp := test.Exp
+			if test.ExpRave != "" {
+				exp = test.ExpRave
+			}
+			rave, _ := raveRoot(t, test.Name, 5000, true, 1)
+			require.Equal(t, exp, rave.RobustSafeMove(rootIdx))
+		})
+	}
+}
+
+// TestRaveAMAF checks that RAVE finds the move of a long search early in the solo end
+// game of 015, where the moves of the few remaining paths share AMAF statistics.
+func TestRaveAMAF(t *testing.T) {
+	const file = "../testdata/input-015.json"
+
+	plain, rootIdx := raveRoot(t, file, 5000, false, 4)
+	require.Equal(t, "down", plain.RobustSafeMove(rootIdx))
+
+	// Plain is still misled after 200 iterations.
+	plain, _ = raveRoot(t, file, 200, false, 4)
+	require.Equal(t, "right", plain.RobustSafeMove(rootIdx))
+
+	rave, _ := raveRoot(t, file, 200, true, 4)
+	require.Equal(t, "down", rave.RobustSafeMove(rootIdx))
+}
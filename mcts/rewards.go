@@ -0,0 +1,77 @@
+package mcts
+
+import "math"
+
+// shapeRewards returns the terminal rewards shaped by Placement and Discount. Deaths are
+// the plies at which snakes were eliminated, -1 if alive, and depth is the ply of the terminal.
+func (o *Opts) shapeRewards(res []float64, deaths []int, depth int) []float64 {
+	if o.Placement && len(deaths) > 1 {
+		res = placementRewards(deaths)
+	}
+
+	if o.Discount > 0 {
+		f := math.Pow(1-o.Discount, float64(depth))
+		for i := range res {
+			res[i] *= f
+		}
+	}
+
+	return res
+}
+
+// shapedRewards returns true if terminal rewards are shaped.
+func (o *Opts) shapedRewards() bool {
+	return o.Placement || o.Discount > 0
+}
+
+// placementRewards returns the rewards by finishing place, from 1 for first to -1 for last.
+// Alive snakes place above dead snakes and snakes that died later above those that died
+// earlier. Snakes that died on the same ply share their places.
+func placementRewards(deaths []int) []float64 {
+	outlived := func(a, b int) bool {
+		if a == -1 {
+			return b != -1
+		}
+		return b != -1 && a > b
+	}
+
+	l := len(deaths)
+	res := make([]float64, l)
+	for i := range deaths {
+		var above, shared int
+		for j := range deaths {
+			if j == i {
+				continue
+			}
+			if outlived(deaths[j], deaths[i]) {
+				above++
+			} else if deaths[j] == deaths[i] {
+				shared++
+			}
+		}
+
+		place := float64(above) + float64(shared)/2
+		res[i] = 1 - 2*place/float64(l-1)
+	}
+
+	return res
+}
+
+// deaths returns the depth at which each snake was eliminated on the path to n, -1 if alive.
+func (n *node) deaths() []int {
+	res := make([]int, len(n.board.Snakes))
+	for i := range res {
+		res[i] = -1
+		if n.board.Snakes[i].EliminatedCause == "" {
+			continue
+		}
+
+		dead := n
+		for dead.parent != nil && dead.parent.board.Snakes[i].EliminatedCause != "" {
+			dead = dead.parent
+		}
+		res[i] = dead.depth
+	}
+
+	return res
+}
@@ -0,0 +1,74 @@
+package mcts
+
+import (
+	"testing"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlacementRewards(t *testing.T) {
+	tests := []struct {
+		Deaths []int
+		Exp    []float64
+	}{
+		{Deaths: []int{-1, 2}, Exp: []float64{1, -1}},
+		{Deaths: []int{2, 2}, Exp: []float64{0, 0}},
+		{Deaths: []int{-1, 3, 1}, Exp: []float64{1, 0, -1}},
+		{Deaths: []int{-1, -1, 1}, Exp: []float64{0.5, 0.5, -1}},
+		{Deaths: []int{1, 3, 1, -1}, Exp: []float64{-2.0 / 3, 1.0 / 3, -2.0 / 3, 1}},
+	}
+
+	for _, test := range tests {
+		res := placementRewards(test.Deaths)
+		require.InDeltaSlice(t, test.Exp, res, 1e-9, "deaths=%v", test.Deaths)
+	}
+}
+
+func TestCheckTerminalShaped(t *testing.T) {
+	snakes := func(dead ...int) []rules.Snake {
+		res := []rules.Snake{
+			{ID: "a", Body: []rules.Point{{X: 0, Y: 0}}, Health: 100},
+			{ID: "b", Body: []rules.Point{{X: 2, Y: 2}}, Health: 100},
+			{ID: "c", Body: []rules.Point{{X: 4, Y: 4}}, Health: 100},
+		}
+		for _, i := range dead {
+			res[i].EliminatedCause = rules.EliminatedByOutOfBounds
+		}
+		return res
+	}
+
+	root := NewRoot(&rules.StandardRuleset{}, &rules.BoardState{Width: 5, Height: 5, Snakes: snakes()}, 0)
+	child := &node{ruleset: root.ruleset, idsByIdx: root.idsByIdx, parent: root, depth: 1,
+		board: &rules.BoardState{Width: 5, Height: 5, Snakes: snakes(2)}}
+	leaf := &node{ruleset: root.ruleset, idsByIdx: root.idsByIdx, parent: child, depth: 2,
+		board: &rules.BoardState{Width: 5, Height: 5, Snakes: snakes(1, 2)}}
+
+	require.Equal(t, []int{-1, 2, 1}, leaf.deaths())
+
+	tests := []struct {
+		Name string
+		Opts Opts
+		Exp  []float64
+	}{
+		{Name: "default", Exp: []float64{1, -1, -1}},
+		{Name: "placement", Opts: Opts{Placement: true}, Exp: []float64{1, 0, -1}},
+		{Name: "discount", Opts: Opts{Discount: 0.1}, Exp: []float64{0.81, -0.81, -0.81}},
+		{Name: "both", Opts: Opts{Placement: true, Discount: 0.1}, Exp: []float64{0.81, 0, -0.81}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			res, ok, err := leaf.CheckTerminal(&test.Opts)
+			jtest.RequireNil(t, err)
+			require.True(t, ok)
+			require.InDeltaSlice(t, test.Exp, res, 1e-9)
+		})
+	}
+
+	// Root snake alive and not over, not terminal.
+	_, ok, err := child.CheckTerminal(&Opts{Placement: true})
+	jtest.RequireNil(t, err)
+	require.False(t, ok)
+}
@@ -0,0 +1,261 @@
+package mcts
+
+import "github.com/corverroos/bsnake/board"
+
+// proof is the proven game theoretic result of a node for the root snake.
+type proof int8
+
+const (
+	unproven   proof = 0
+	provenWin  proof = 1
+	provenLoss proof = 2
+	provenDraw proof = 3
+)
+
+func (p proof) String() string {
+	switch p {
+	case unproven:
+		return "unproven"
+	case provenWin:
+		return "win"
+	case provenLoss:
+		return "loss"
+	case provenDraw:
+		return "draw"
+	default:
+		return "invalid"
+	}
+}
+
+// terminalProof returns the proof of the terminal node for the root snake: a loss if it
+// was eliminated, a draw if all snakes were, otherwise a win. It doesn't depend on the
+// shaped totals, e.g. a placement reward above zero for finishing second of four is a loss.
+func terminalProof(n *node) proof {
+	if n.board.Snakes[n.rootIdx].EliminatedCause == "" {
+		return provenWin
+	}
+
+	if len(n.board.Snakes) > 1 {
+		for _, s := range n.board.Snakes {
+			if s.EliminatedCause == "" {
+				return provenLoss
+			}
+		}
+		return provenDraw
+	}
+
+	return provenLoss
+}
+
+// calcProof returns the proof of a node from its children under simultaneous moves.
+//   - win: the root snake has a move that wins against all opponent replies.
+//   - loss: the opponents have a joint move that wins against all root snake moves.
+//   - draw: the root snake can ensure at least a draw and the opponents at most a draw.
+func (n *node) calcProof() proof {
+	if n.IsLeaf() {
+		return unproven
+	}
+
+	type agg struct {
+		all       bool // All children are a win (by root move) or a loss (by opponent move)
+		allOrDraw bool // All children are a win/loss or a draw
+	}
+
+	mask := edge(0b111 << (n.rootIdx * 3))
+	byRoot := make(map[edge]agg)
+	byOpp := make(map[edge]agg)
+
+	for _, tup := range n.childs {
+		p := tup.child.proof
+
+		r, ok := byRoot[tup.edge&mask]
+		if !ok {
+			r = agg{all: true, allOrDraw: true}
+		}
+		r.all = r.all && p == provenWin
+		r.allOrDraw = r.allOrDraw && (p == provenWin || p == provenDraw)
+		byRoot[tup.edge&mask] = r
+
+		o, ok := byOpp[tup.edge&^mask]
+		if !ok {
+			o = agg{all: true, allOrDraw: true}
+		}
+		o.all = o.all && p == provenLoss
+		o.allOrDraw = o.allOrDraw && (p == provenLoss || p == provenDraw)
+		byOpp[tup.edge&^mask] = o
+	}
+
+	var atLeastDraw, atMostDraw bool
+	for _, r := range byRoot {
+		if r.all {
+			return provenWin
+		}
+		atLeastDraw = atLeastDraw || r.allOrDraw
+	}
+	for _, o := range byOpp {
+		if o.all {
+			return provenLoss
+		}
+		atMostDraw = atMostDraw || o.allOrDraw
+	}
+
+	if atLeastDraw && atMostDraw {
+		return provenDraw
+	}
+
+	return unproven
+}
+
+// proveTerminal marks the terminal node proven and propagates the proof
+// up the tree until a parent cannot be proven.
+func proveTerminal(n *node) {
+	n.proof = terminalProof(n)
+	n.proofValue = n.termTotals[n.rootIdx]
+	for n = n.parent; n != nil; n = n.parent {
+		p := n.calcProof()
+		if p == unproven || p == n.proof {
+			return
+		}
+		n.proof = p
+		n.proofValue = n.calcProofValue()
+	}
+}
+
+// calcProofValue returns the root snake's terminal value guaranteed by the proof of n: the
+// best worst case of the root snake moves that win or draw, or the worst best case of the
+// opponent moves that win.
+func (n *node) calcProofValue() float64 {
+	mask := edge(0b111 << (n.rootIdx * 3))
+
+	// groups returns the worst (or best) child value of each move group if all its
+	// children have one of the proofs.
+	groups := func(mask edge, worst bool, proofs ...proof) []float64 {
+		values := make(map[edge]float64)
+		excluded := make(map[edge]bool)
+		for _, tup := range n.childs {
+			e := tup.edge & mask
+			ok := false
+			for _, p := range proofs {
+				ok = ok || tup.child.proof == p
+			}
+			if !ok {
+				excluded[e] = true
+				continue
+			}
+
+			v, found := values[e]
+			if !found || (worst && tup.child.proofValue < v) || (!worst && tup.child.proofValue > v) {
+				values[e] = tup.child.proofValue
+			}
+		}
+
+		var res []float64
+		for e, v := range values {
+			if !excluded[e] {
+				res = append(res, v)
+			}
+		}
+		return res
+	}
+
+	var (
+		values []float64
+		max    bool
+	)
+	switch n.proof {
+	case provenWin:
+		values, max = groups(mask, true, provenWin), true
+	case provenLoss:
+		values, max = groups(^mask, false, provenLoss), false
+	case provenDraw:
+		values, max = groups(mask, true, provenWin, provenDraw), true
+	}
+
+	var res float64
+	for i, v := range values {
+		if i == 0 || (max && v > res) || (!max && v < res) {
+			res = v
+		}
+	}
+	return res
+}
+
+// proofTotals returns the totals to propagate for a proven node; the proven shaped
+// terminal value for the root snake and the average score for the others.
+func (n *node) proofTotals() []float64 {
+	res := make([]float64, len(n.idsByIdx))
+	for i := range res {
+		res[i] = n.AvgScore(i)
+	}
+
+	res[n.rootIdx] = n.proofValue
+
+	return res
+}
+
+// provenLossMoves returns the root snake moves that are proven losses against
+// all opponent replies.
+func (n *node) provenLossMoves() map[string]bool {
+	res := make(map[string]bool)
+	for _, move := range board.Moves {
+		var found bool
+		loss := true
+		for _, tup := range n.childs {
+			if !tup.edge.Is(n.rootIdx, move) {
+				continue
+			}
+			found = true
+			if tup.child.proof != provenLoss {
+				loss = false
+				break
+			}
+		}
+		if found && loss {
+			res[move] = true
+		}
+	}
+	return res
+}
+
+// provenWinMove returns a root snake move that is a proven win against all opponent replies.
+func (n *node) provenWinMove() (string, bool) {
+	for _, move := range board.Moves {
+		var found bool
+		win := true
+		for _, tup := range n.childs {
+			if !tup.edge.Is(n.rootIdx, move) {
+				continue
+			}
+			found = true
+			if tup.child.proof != provenWin {
+				win = false
+				break
+			}
+		}
+		if found && win {
+			return move, true
+		}
+	}
+	return "", false
+}
+
+// SolvedMove returns a proven winning move if any, otherwise the provided move if it isn't a
+// proven loss, otherwise the most robust move that isn't a proven loss.
+func (n *node) SolvedMove(move string) string {
+	if win, ok := n.provenWinMove(); ok {
+		return win
+	}
+
+	losses := n.provenLossMoves()
+	if !losses[move] {
+		return move
+	}
+
+	for _, m := range n.RobustMoves(n.rootIdx) {
+		if !losses[m] {
+			return m
+		}
+	}
+
+	return move
+}
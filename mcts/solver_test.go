@@ -0,0 +1,147 @@
+package mcts
+
+import (
+	"testing"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalcProof(t *testing.T) {
+	const win, loss, draw, none = provenWin, provenLoss, provenDraw, unproven
+
+	tests := []struct {
+		Name   string
+		Childs map[edge]proof // Root snake at idx 0
+		Exp    proof
+	}{
+		{
+			Name:   "root move wins all replies",
+			Childs: map[edge]proof{m2e(u, u): win, m2e(u, d): win, m2e(l, u): loss, m2e(l, d): none},
+			Exp:    provenWin,
+		},
+		{
+			Name:   "opponent move wins all root moves",
+			Childs: map[edge]proof{m2e(u, u): loss, m2e(u, d): win, m2e(l, u): loss, m2e(l, d): win},
+			Exp:    provenLoss,
+		},
+		{
+			Name:   "each root move loses to a different reply",
+			Childs: map[edge]proof{m2e(u, u): loss, m2e(u, d): win, m2e(l, u): win, m2e(l, d): loss},
+			Exp:    unproven,
+		},
+		{
+			Name:   "draw",
+			Childs: map[edge]proof{m2e(u, u): draw, m2e(u, d): win, m2e(l, u): draw, m2e(l, d): loss},
+			Exp:    provenDraw,
+		},
+		{
+			Name:   "unproven reply",
+			Childs: map[edge]proof{m2e(u, u): win, m2e(u, d): none, m2e(l, u): loss, m2e(l, d): win},
+			Exp:    unproven,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			n := &node{}
+			for e, p := range test.Childs {
+				n.childs = append(n.childs, tuple{edge: e, child: &node{proof: p}})
+			}
+			require.Equal(t, test.Exp, n.calcProof())
+		})
+	}
+}
+
+func TestSolver(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Proof  proof
+		Losses map[string]bool
+		Move   string
+	}{
+		{
+			Name:   "../testdata/input-008.json",
+			Proof:  unproven,
+			Losses: map[string]bool{"left": true},
+			Move:   "right",
+		},
+		{
+			Name:   "../testdata/input-011.json",
+			Proof:  provenLoss,
+			Losses: map[string]bool{"left": true, "up": true},
+		},
+		{
+			Name:  "../testdata/input-016.json",
+			Proof: provenWin,
+			Move:  "up",
+		},
+		{
+			Name:  "../testdata/input-021.json",
+			Proof: provenDraw,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			b, rootIdx := fileToBoard(t, test.Name)
+			root := NewRoot(&rules.StandardRuleset{}, b, rootIdx)
+
+			o := OptsV5
+			o.Solver = true
+			for i := 0; i < 5000 && root.proof == unproven; i++ {
+				jtest.RequireNil(t, Once(root, &o))
+			}
+
+			require.Equal(t, test.Proof, root.proof)
+			if test.Losses != nil {
+				require.Equal(t, test.Losses, root.provenLossMoves())
+			}
+			if test.Move != "" {
+				require.Equal(t, test.Move, root.SolvedMove(root.RobustMoves(rootIdx)[0]))
+			}
+		})
+	}
+}
+
+func TestProofShaped(t *testing.T) {
+	snakes := func(dead ...int) []rules.Snake {
+		res := []rules.Snake{
+			{ID: "a", Body: []rules.Point{{X: 0, Y: 0}}, Health: 100},
+			{ID: "b", Body: []rules.Point{{X: 2, Y: 2}}, Health: 100},
+			{ID: "c", Body: []rules.Point{{X: 4, Y: 4}}, Health: 100},
+			{ID: "d", Body: []rules.Point{{X: 0, Y: 4}}, Health: 100},
+		}
+		for _, i := range dead {
+			res[i].EliminatedCause = rules.EliminatedByOutOfBounds
+		}
+		return res
+	}
+
+	// The root snake "a" dies second of four, after "b" and "c".
+	root := NewRoot(&rules.StandardRuleset{}, &rules.BoardState{Width: 5, Height: 5, Snakes: snakes()}, 0)
+	child := &node{ruleset: root.ruleset, idsByIdx: root.idsByIdx, rootIdx: 0, parent: root, depth: 1,
+		board: &rules.BoardState{Width: 5, Height: 5, Snakes: snakes(1, 2)}}
+	leaf := &node{ruleset: root.ruleset, idsByIdx: root.idsByIdx, rootIdx: 0, parent: child, depth: 2,
+		board: &rules.BoardState{Width: 5, Height: 5, Snakes: snakes(0, 1, 2)}}
+	child.childs = []tuple{{child: leaf}}
+	root.childs = []tuple{{child: child}}
+
+	o := &Opts{Placement: true, Discount: 0.1}
+	totals, ok, err := leaf.CheckTerminal(o)
+	jtest.RequireNil(t, err)
+	require.True(t, ok)
+	require.InDelta(t, 0.81/3, totals[0], 1e-9)
+
+	// A loss, although the placement reward is above zero.
+	leaf.termTotals = totals
+	proveTerminal(leaf)
+	require.Equal(t, provenLoss, leaf.proof)
+	require.Equal(t, provenLoss, child.proof)
+	require.Equal(t, provenLoss, root.proof)
+
+	// The shaped value is propagated.
+	require.InDelta(t, 0.81/3, child.proofTotals()[0], 1e-9)
+	require.InDelta(t, 0.81/3, root.proofTotals()[0], 1e-9)
+}
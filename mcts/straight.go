@@ -0,0 +1,65 @@
+package mcts
+
+import (
+	"github.com/BattlesnakeOfficial/rules"
+
+	"github.com/corverroos/bsnake/board"
+)
+
+// straightMove returns the move continuing the snake's direction. This is also the move
+// the engine applies when a snake times out. It defaults to up if the snake has no
+// direction yet.
+func straightMove(b *rules.BoardState, snakeIdx int) string {
+	body := b.Snakes[snakeIdx].Body
+	if len(body) < 2 || body[0] == body[1] {
+		return "up"
+	}
+
+	for _, move := range board.Moves {
+		if board.MovePoint(body[1], move) == body[0] {
+			return move
+		}
+	}
+
+	return "up"
+}
+
+// isStraight returns true if the snake is modelled as moving straight, see Opts.Straight.
+func (o *Opts) isStraight(b *rules.BoardState, snakeIdx int) bool {
+	return len(o.Straight) > 0 && b.Snakes[snakeIdx].EliminatedCause == "" && o.Straight[b.Snakes[snakeIdx].ID]
+}
+
+// straightMoveSet returns the move sets with the moves of snakes in Opts.Straight
+// replaced by their straight moves, removing duplicates.
+func (o *Opts) straightMoveSet(b *rules.BoardState, moveSet [][]string) [][]string {
+	for i := range b.Snakes {
+		if !o.isStraight(b, i) || len(moveSet) == 0 {
+			continue
+		}
+
+		// Move sets are the product of snake moves, so keep one move's sets.
+		first := moveSet[0][i]
+		move := straightMove(b, i)
+
+		var res [][]string
+		for _, moves := range moveSet {
+			if moves[i] != first {
+				continue
+			}
+			moves[i] = move
+			res = append(res, moves)
+		}
+		moveSet = res
+	}
+
+	return moveSet
+}
+
+// straightenMoves replaces the play-out moves of snakes in Opts.Straight by their straight moves.
+func (o *Opts) straightenMoves(b *rules.BoardState, moves []rules.SnakeMove) {
+	for i := range moves {
+		if moves[i].Move != "" && o.isStraight(b, i) {
+			moves[i].Move = straightMove(b, i)
+		}
+	}
+}
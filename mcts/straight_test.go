@@ -0,0 +1,39 @@
+package mcts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/corverroos/bsnake/board"
+)
+
+func TestStraight(t *testing.T) {
+	b, rootIdx := fileToBoard(t, "../testdata/input-027.json")
+	oppIdx := 1 - rootIdx
+	straight := straightMove(b, oppIdx)
+
+	o := Opts{Straight: map[string]bool{b.Snakes[oppIdx].ID: true}}
+
+	root := NewRoot(nil, b, rootIdx)
+	all := genMoveSet(root, &Opts{})
+	sets := genMoveSet(root, &o)
+	require.Less(t, len(sets), len(all))
+
+	var rootMoves []string
+	for _, moves := range sets {
+		require.Equal(t, straight, moves[oppIdx])
+		rootMoves = append(rootMoves, moves[rootIdx])
+	}
+
+	var expRoot []string
+	for _, move := range board.Moves {
+		for _, moves := range all {
+			if moves[rootIdx] == move {
+				expRoot = append(expRoot, move)
+				break
+			}
+		}
+	}
+	require.ElementsMatch(t, expRoot, rootMoves)
+}
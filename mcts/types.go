@@ -2,7 +2,9 @@ package mcts
 
 import (
 	"math"
+	"math/rand"
 	"sort"
+	"time"
 
 	"github.com/BattlesnakeOfficial/rules"
 
@@ -96,6 +98,15 @@ type node struct {
 
 	termTotals []float64
 	heurTotals []float64
+
+	proof      proof         // Proven result for the root snake, see MCTS-Solver.
+	proofValue float64       // Root snake's shaped terminal value guaranteed by the proof.
+	priors     [][]float64   // Move priors by snake, see PUCT.
+	bandits    []banditStats // Mixed strategy statistics by snake, see Exp3 and regret matching.
+	rave       []raveStats   // All-moves-as-first statistics by snake, see RAVE.
+	tree       *tree         // Shared by all nodes in the tree, see MaxNodes.
+	edge       edge          // Edge from the parent to the node.
+	stats      *nodeStats    // Cached child aggregates, nil if not built yet.
 }
 
 func (n *node) MinMaxMove(idx int) string {
@@ -117,8 +128,9 @@ func (n *node) MinMaxMove(idx int) string {
 		max = float64(math.MinInt32)
 	)
 
-	for move, min := range mins {
-		if min > max {
+	for _, move := range board.Moves {
+		min, ok := mins[move]
+		if ok && min > max {
 			res = move
 			max = min
 		}
@@ -163,12 +175,10 @@ func (n *node) RobustMoves(idx int) []string {
 
 	totals := make([]tup, len(board.Moves))
 
-	for _, tuple := range n.childs {
-		for i, move := range board.Moves {
-			if tuple.edge.Is(idx, move) {
-				totals[i].K = move
-				totals[i].V += tuple.child.n
-			}
+	for i, st := range n.getStats().moves[idx] {
+		if st.childs > 0 {
+			totals[i].K = board.Moves[i]
+			totals[i].V = st.n
 		}
 	}
 
@@ -206,7 +216,18 @@ func (n *node) IsTerminal() bool {
 	return len(n.termTotals) > 0
 }
 
-func (n *node) CheckTerminal() ([]float64, bool, error) {
+// CheckTerminal returns the terminal rewards of the node if it is terminal.
+// The rewards are shaped by the Placement and Discount options.
+func (n *node) CheckTerminal(o *Opts) ([]float64, bool, error) {
+	res, ok, err := n.checkTerminal()
+	if err != nil || !ok || !o.shapedRewards() {
+		return res, ok, err
+	}
+
+	return o.shapeRewards(res, n.deaths(), n.depth), true, nil
+}
+
+func (n *node) checkTerminal() ([]float64, bool, error) {
 	l := len(n.board.Snakes)
 
 	over, err := n.ruleset.IsGameOver(n.board)
@@ -304,19 +325,17 @@ func genChild(n *node, moves []string) (tuple, error) {
 		return tuple{}, err
 	}
 
-	child := &node{
-		ruleset:      n.ruleset,
-		idsByIdx:     n.idsByIdx,
-		rootIdx:      n.rootIdx,
-		board:        board,
-		depth:        n.depth + 1,
-		lastMoves:    moves,
-		parent:       n,
-		childs:       make([]tuple, 0, 64),
-		totals:       make([]float64, len(n.idsByIdx)),
-		totalSquares: make([]float64, len(n.idsByIdx)),
-		heurTotals:   make([]float64, len(n.idsByIdx)),
-	}
+	child := allocNode(len(n.idsByIdx))
+	child.ruleset = n.ruleset
+	child.idsByIdx = n.idsByIdx
+	child.rootIdx = n.rootIdx
+	child.board = board
+	child.depth = n.depth + 1
+	child.lastMoves = moves
+	child.edge = e
+	child.parent = n
+	child.tree = n.tree
+	child.tree.nodes++
 
 	return tuple{edge: e, child: child}, nil
 }
@@ -328,6 +347,9 @@ func (n *node) AppendChild(moves []string) (*node, error) {
 	}
 
 	n.childs = append(n.childs, tup)
+	if n.stats != nil {
+		n.stats.addChild(tup.edge)
+	}
 
 	return tup.child, nil
 }
@@ -337,17 +359,15 @@ func NewRoot(ruleset rules.Ruleset, board *rules.BoardState, rootIdx int) *node
 	for idx, snake := range board.Snakes {
 		idsByIdx[idx] = snake.ID
 	}
-	return &node{
-		ruleset:      ruleset,
-		idsByIdx:     idsByIdx,
-		rootIdx:      rootIdx,
-		board:        board,
-		n:            1,
-		childs:       make([]tuple, 0, 64),
-		totals:       make([]float64, len(idsByIdx)),
-		totalSquares: make([]float64, len(idsByIdx)),
-		heurTotals:   make([]float64, len(idsByIdx)),
-	}
+	root := allocNode(len(idsByIdx))
+	root.ruleset = ruleset
+	root.idsByIdx = idsByIdx
+	root.rootIdx = rootIdx
+	root.board = board
+	root.n = 1
+	root.tree = &tree{nodes: 1}
+
+	return root
 }
 
 var (
@@ -434,6 +454,82 @@ var (
 		},
 	}
 
+	// OptsV5P is OptsV5 stopping early once the root decision is settled, leaving the
+	// remaining time to pondering.
+	OptsV5P = Opts{
+		Tuned:        true,
+		Version:      2,
+		UCB1_C:       4,
+		SelectRandom: 20,
+		LeafPlayout:  false,
+		LeafHeur:     true,
+		EarlyStop:    true,
+		HeurFactors: &heur.Factors{
+			Control: 0.05,
+			Length:  0.4,
+			Boxed:   -0.5,
+			Hunger:  -0.001,
+			Starve:  -0.9,
+		},
+	}
+
+	// OptsV5O is OptsV5 with PUCT selection of the opponents' moves by the Opts.Opponents
+	// model priors and short model play-outs of the opponents from the leaves.
+	OptsV5O = Opts{
+		Tuned:          true,
+		Version:        2,
+		UCB1_C:         4,
+		SelectRandom:   20,
+		Bandit:         BanditPUCT,
+		PUCT_C:         1.5,
+		LeafPlayout:    true,
+		MaxPlayout:     5,
+		PlayoutMaxHeur: true,
+		PlayoutOpp:     "model",
+		HeurFactors: &heur.Factors{
+			Control: 0.05,
+			Length:  0.4,
+			Boxed:   -0.5,
+			Hunger:  -0.001,
+			Starve:  -0.9,
+		},
+	}
+
+	// OptsMx0 to OptsMx3 are the alpha-beta minimax presets, which only use the
+	// heuristic factors, see SelectMinimax.
+	OptsMx0 = Opts{
+		HeurFactors: &heur.Factors{
+			Control: 0.01,
+			Length:  0.5,
+			Hunger:  -0.001,
+			Starve:  -0.9,
+		},
+	}
+	OptsMx1 = Opts{
+		HeurFactors: &heur.Factors{
+			Control: 0.1,
+			Length:  0.3,
+			Hunger:  -0.02,
+			Starve:  -0.8,
+		},
+	}
+	OptsMx2 = Opts{
+		HeurFactors: &heur.Factors{
+			Control: 0.1,
+			Length:  0.3,
+			Hunger:  -0.001,
+			Starve:  -0.9,
+		},
+	}
+	OptsMx3 = Opts{
+		HeurFactors: &heur.Factors{
+			Control: 0.05,
+			Length:  0.35,
+			Hunger:  -0.001,
+			Starve:  -0.9,
+		},
+	}
+
 	//        v3 p=19 w=map[total:5 v4:5 v5:5]        l=map[total:14 v4:9 v5:5]
 	//        v4 p=19 w=map[total:9 v3:9 v5:9]        l=map[total:10 v3:5 v5:5]
 	//        v5 p=19 w=map[total:5 v3:5 v4:5]        l=map[total:14 v3:5 v4:9]
@@ -447,7 +543,7 @@ type Opts struct {
 	SelectHeur     bool    // Use heuristics during select (progressive bias)
 	HeurFactors    *heur.Factors
 	GreedyProb     float64
-	GreedyHeur     func(*rules.BoardState, int) string        `json:"-"`
+	GreedyHeur     func(*rules.BoardState, int) string        `json:"-"` // Defaults to the greedy play-out policy without randomness.
 	logd           func(string, ...interface{})               `json:"-"`
 	logr           func(root *node, rootIdx int, move string) `json:"-"`
 	Tuned          bool
@@ -456,6 +552,41 @@ type Opts struct {
 	LeafPlayout    bool
 	LeafHeur       bool
 	AvoidLH2H      bool
+	Solver         bool          // Propagate proven wins and losses (MCTS-Solver)
+	MxBackup       Backup        // Multiplayer minimax backup rule used by Minimax and MxOnce.
+	Bandit         Bandit        // Per snake move selection policy, defaults to UCB1.
+	PUCT_C         float64       // Prior exploration factor of BanditPUCT, defaults to 1.5.
+	BanditGamma    float64       // Exploration of Exp3 and regret matching, defaults to 0.1.
+	SampleFinal    bool          // Sample the final move from the root snake's mixed strategy.
+	Final          Final         // Final move selection strategy.
+	SecureA        float64       // Confidence factor of FinalSecure, defaults to 1.
+	FinalExtend    time.Duration // Maximum search extension of FinalRobustMax within the budget, defaults to 50ms.
+	Rave           bool          // Blend all-moves-as-first statistics into selection (RAVE).
+	RaveK          float64       // RAVE equivalence parameter, defaults to 500.
+	Playout        string        // Play-out policy name of the root snake, see Playouts, defaults to random.
+	PlayoutOpp     string        // Play-out policy name of the opponents, defaults to Playout.
+	PlayoutEpsilon float64       // Random move probability of the greedy play-out policy, defaults to 0.1.
+	PlayoutHunger  int32         // Health at or below which the food play-out policy seeks food, defaults to 30.
+	EarlyStop      bool          // Stop searching once the root decision is settled, see SelectMoveStop.
+	PonderTime     time.Duration // Maximum time to ponder between turns, defaults to 1s.
+	PonderNodes    int           // Maximum tree size while pondering, defaults to 500k.
+	MaxNodes       int           // Maximum tree size, caps the tree by evaluating leaves without expansion when reached, nothing is pruned.
+	Placement      bool          // Multiplayer terminal rewards by finishing place instead of win/loss.
+	Discount       float64       // Terminal reward discount per ply, preferring quick wins and slow losses.
+
+	// Move models, e.g. per game opponent models, not serialised.
+	Policy    Policy          `json:"-"` // Move priors for PUCT, defaults to uniform.
+	Opponents Policy          `json:"-"` // Opponent move model, overrides Policy for opponents, see BackupExpectimax and the model play-out.
+	Straight  map[string]bool `json:"-"` // IDs of opponents modelled as only moving straight, e.g. after repeated timeouts.
+
+	// Random source of the search, defaults to the global source. Not safe for concurrent use, e.g. pondering.
+	Rand *rand.Rand `json:"-"`
+}
+
+// IsMinimax returns true if the options have no MCTS leaf strategy, like the
+// alpha-beta minimax presets, see SelectMinimax.
+func (o Opts) IsMinimax() bool {
+	return !o.LeafPlayout && !o.LeafHeur
 }
 
 func (o *Opts) Logd(msg string, args ...interface{}) {
@@ -471,3 +602,33 @@ func (o *Opts) LogResults(root *node, rootIdx int, move string) {
 	}
 	o.logr(root, rootIdx, move)
 }
+
+// SetLogf logs the results of each search, the root snake's move statistics, to fn.
+func (o *Opts) SetLogf(fn func(string, ...interface{})) {
+	o.logr = func(root *node, rootIdx int, move string) {
+		fn("results: move=%s iterations=%.0f nodes=%d", move, root.n, root.tree.nodes)
+		for midx, st := range root.getStats().moves[rootIdx] {
+			if st.childs == 0 {
+				continue
+			}
+			move := board.Moves[midx]
+			fn("\t%s visits=%.0f min=%.3f", move, st.n, root.MinAvgScore(rootIdx, move))
+		}
+	}
+}
+
+// float64 returns a pseudo-random number in [0.0,1.0) from the search's random source.
+func (o *Opts) float64() float64 {
+	if o.Rand == nil {
+		return rand.Float64()
+	}
+	return o.Rand.Float64()
+}
+
+// intn returns a pseudo-random number in [0,n) from the search's random source.
+func (o *Opts) intn(n int) int {
+	if o.Rand == nil {
+		return rand.Intn(n)
+	}
+	return o.Rand.Intn(n)
+}
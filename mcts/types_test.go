@@ -1,12 +1,14 @@
 package mcts
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/rand"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/BattlesnakeOfficial/rules"
 	"github.com/luno/jettison/jtest"
@@ -54,7 +56,7 @@ func Test500Once(t *testing.T) {
 		},
 		{
 			Name: "../testdata/input-027.json",
-			Exp:  "left",
+			Exp:  "down", // Left is a possible loosing h2h
 		},
 		{
 			Name: "../testdata/input-028.json",
@@ -132,7 +134,7 @@ func Test500Once(t *testing.T) {
 
 			opts.logr(root, rootIdx, "")
 
-			if !strings.Contains(t.Name(), "-021") && !strings.Contains(t.Name(), "-027") {
+			if !strings.Contains(t.Name(), "-021") {
 				require.Equal(t, test.Exp, root.MinMaxMove(rootIdx))
 				require.Equal(t, test.Exp, root.RobustMoves(rootIdx)[0])
 			}
@@ -260,6 +262,47 @@ func TestGenMoves(t *testing.T) {
 	}
 }
 
+func TestAvoidLH2H(t *testing.T) {
+	tests := []struct {
+		Name string
+		Exp  []string
+	}{
+		{
+			Name: "../testdata/input-021.json",
+			Exp:  []string{"up", "left"}, // No alternatives
+		},
+		{
+			Name: "../testdata/input-027.json",
+			Exp:  []string{"down", "right"},
+		},
+		{
+			Name: "../testdata/input-028.json",
+			Exp:  []string{"up", "right"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			b, rootIdx := fileToBoard(t, test.Name)
+			root := NewRoot(&rules.StandardRuleset{}, b, rootIdx)
+
+			_, err := expansion(root, &Opts{AvoidLH2H: true})
+			jtest.RequireNil(t, err)
+
+			var moves []string
+			for _, move := range board.Moves {
+				for _, tup := range root.childs {
+					if tup.edge.Is(rootIdx, move) {
+						moves = append(moves, move)
+						break
+					}
+				}
+			}
+			require.Equal(t, test.Exp, moves)
+		})
+	}
+}
+
 func TestPlayoutRational(t *testing.T) {
 	tests := []struct {
 		Name string
@@ -280,7 +323,7 @@ func TestPlayoutRational(t *testing.T) {
 			n0 := NewRoot(&rules.StandardRuleset{}, board, rootIdx)
 
 			rand.Seed(0)
-			totals, err := playoutRandomRational(n0, n0, &OptsV1)
+			totals, err := playoutRandomRational(n0, n0, &OptsV1, nil)
 			jtest.RequireNil(t, err)
 			require.EqualValues(t, test.Exp, totals)
 		})
@@ -352,16 +395,16 @@ func TestFileToBoard(t *testing.T) {
 	fileToBoard(t, "../testdata/input-020.json")
 }
 
-func fileToBoard(t *testing.T, file string) (*rules.BoardState, int) {
+func fileToBoard(t testing.TB, file string) (*rules.BoardState, int) {
 	f, err := os.Open(file)
-	jtest.RequireNil(t, err)
+	require.NoError(t, err)
 	var req struct {
 		Board rules.BoardState
 		You   struct {
 			ID string
 		}
 	}
-	jtest.RequireNil(t, json.NewDecoder(f).Decode(&req))
+	require.NoError(t, json.NewDecoder(f).Decode(&req))
 
 	youIDx := -1
 	for i, snake := range req.Board.Snakes {
@@ -374,3 +417,16 @@ func fileToBoard(t *testing.T, file string) (*rules.BoardState, int) {
 
 	return &req.Board, youIDx
 }
+
+func TestBudget(t *testing.T) {
+	t0 := time.Now()
+	require.Equal(t, defaultBudget, budget(context.Background(), t0))
+
+	ctx, cancel := context.WithDeadline(context.Background(), t0.Add(time.Millisecond*500))
+	defer cancel()
+	require.Equal(t, time.Millisecond*340, budget(ctx, t0))
+
+	ctx, cancel = context.WithDeadline(context.Background(), t0.Add(time.Millisecond*90))
+	defer cancel()
+	require.Equal(t, time.Millisecond*60, budget(ctx, t0))
+}
@@ -0,0 +1,325 @@
+// Package opp models opponent move tendencies learned from observed moves.
+package opp
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/BattlesnakeOfficial/rules"
+
+	"github.com/corverroos/bsnake/board"
+)
+
+// Move features.
+const (
+	Food     = 0 // Moves towards the closest food.
+	H2H      = 1 // Moves next to another snake's head.
+	Straight = 2 // Continues in the same direction.
+
+	numFeatures = 3
+)
+
+// Profile is the observed move tendencies of a snake.
+type Profile struct {
+	Moves   int                  // Observed moves
+	Taken   [numFeatures]float64 // Moves with the feature when there was a choice
+	Base    [numFeatures]float64 // Expected moves with the feature when moving randomly
+	Choices [numFeatures]float64 // Moves with a choice between moves with and without the feature
+}
+
+// Tendency returns the probability of the snake choosing a move with the feature.
+func (p Profile) Tendency(f int) float64 {
+	return (p.Taken[f] + 1) / (p.Choices[f] + 2)
+}
+
+// Probs returns the move probabilities of the snake in board.Moves order. Each feature
+// weights moves by the snake's tendency relative to moving randomly; an unobserved
+// snake moves uniformly among rational moves.
+func (p Profile) Probs(b *rules.BoardState, snakeIdx int) []float64 {
+	feats, rational := features(b, snakeIdx)
+	counts := countFeatures(feats, rational)
+
+	res := make([]float64, len(board.Moves))
+	var total float64
+	for midx := range board.Moves {
+		if !rational[midx] {
+			continue
+		}
+
+		w := 1.0
+		for f := 0; f < numFeatures; f++ {
+			if counts[f] == 0 || counts[f] == counts[numFeatures] {
+				// No choice
+				continue
+			}
+
+			t := p.Tendency(f)
+			r := (p.Base[f] + 1) / (p.Choices[f] + 2)
+			if feats[midx][f] {
+				w *= t / r
+			} else {
+				w *= (1 - t) / (1 - r)
+			}
+		}
+
+		res[midx] = w
+		total += w
+	}
+
+	for midx := range res {
+		if total == 0 {
+			res[midx] = 1 / float64(len(res))
+		} else {
+			res[midx] /= total
+		}
+	}
+
+	return res
+}
+
+// observe updates the profile with the snake's move on the board.
+func (p *Profile) observe(b *rules.BoardState, snakeIdx int, move string) {
+	feats, rational := features(b, snakeIdx)
+	counts := countFeatures(feats, rational)
+
+	var midx int
+	for i, m := range board.Moves {
+		if m == move {
+			midx = i
+		}
+	}
+
+	p.Moves++
+	for f := 0; f < numFeatures; f++ {
+		if counts[f] == 0 || counts[f] == counts[numFeatures] {
+			continue
+		}
+		p.Choices[f]++
+		p.Base[f] += float64(counts[f]) / float64(counts[numFeatures])
+		if feats[midx][f] {
+			p.Taken[f]++
+		}
+	}
+}
+
+// features returns the features and rationality of each move of the snake in board.Moves order.
+func features(b *rules.BoardState, snakeIdx int) ([4][numFeatures]bool, [4]bool) {
+	var (
+		feats    [4][numFeatures]bool
+		rational [4]bool
+	)
+
+	s := b.Snakes[snakeIdx]
+	head := s.Body[0]
+
+	var (
+		food    rules.Point
+		hasFood bool
+	)
+	for _, f := range b.Food {
+		if !hasFood || board.Distance(head, f) < board.Distance(head, food) {
+			food = f
+			hasFood = true
+		}
+	}
+
+	var dir string
+	if len(s.Body) > 1 && s.Body[1] != head {
+		for _, move := range board.Moves {
+			if board.MovePoint(s.Body[1], move) == head {
+				dir = move
+			}
+		}
+	}
+
+	for midx, move := range board.Moves {
+		rational[midx] = board.IsRationalMove(b, snakeIdx, move)
+		next := board.MovePoint(head, move)
+
+		feats[midx][Food] = hasFood && board.Distance(next, food) < board.Distance(head, food)
+		feats[midx][Straight] = move == dir
+
+		for i, other := range b.Snakes {
+			if i != snakeIdx && other.EliminatedCause == "" && board.Distance(next, other.Body[0]) == 1 {
+				feats[midx][H2H] = true
+			}
+		}
+	}
+
+	return feats, rational
+}
+
+// countFeatures returns the number of rational moves with each feature and the
+// number of rational moves at index numFeatures.
+func countFeatures(feats [4][numFeatures]bool, rational [4]bool) [numFeatures + 1]int {
+	var res [numFeatures + 1]int
+	for midx := range feats {
+		if !rational[midx] {
+			continue
+		}
+		res[numFeatures]++
+		for f := 0; f < numFeatures; f++ {
+			if feats[midx][f] {
+				res[f]++
+			}
+		}
+	}
+	return res
+}
+
+// Model learns snake profiles from consecutive boards of games. It is safe for
+// concurrent use.
+type Model struct {
+	mu       sync.Mutex
+	profiles map[string]*Profile // By snake name
+	games    map[string]*game    // By game key
+}
+
+type game struct {
+	last  *rules.BoardState
+	names map[string]string // Snake names by ID
+}
+
+// NewModel returns an empty model.
+func NewModel() *Model {
+	return &Model{
+		profiles: make(map[string]*Profile),
+		games:    make(map[string]*game),
+	}
+}
+
+// Observe updates the profiles of snakes with their moves from the previous board of the
+// game to b. Names are the snake names by ID.
+func (m *Model) Observe(gameKey string, b *rules.BoardState, names map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, ok := m.games[gameKey]
+	if !ok {
+		g = &game{names: make(map[string]string)}
+		m.games[gameKey] = g
+	}
+	for id, name := range names {
+		g.names[id] = name
+	}
+
+	last := g.last
+	g.last = b
+	if last == nil {
+		return
+	}
+
+	for i, prev := range last.Snakes {
+		if prev.EliminatedCause != "" || len(prev.Body) == 0 {
+			continue
+		}
+
+		move, ok := findMove(prev, b)
+		if !ok {
+			continue
+		}
+
+		name := g.names[prev.ID]
+		p, ok := m.profiles[name]
+		if !ok {
+			p = new(Profile)
+			m.profiles[name] = p
+		}
+		p.observe(last, i, move)
+	}
+}
+
+// findMove returns the move of the snake from its previous state to the board.
+func findMove(prev rules.Snake, b *rules.BoardState) (string, bool) {
+	for _, s := range b.Snakes {
+		if s.ID != prev.ID || len(s.Body) == 0 {
+			continue
+		}
+		for _, move := range board.Moves {
+			if board.MovePoint(prev.Body[0], move) == s.Body[0] {
+				return move, true
+			}
+		}
+	}
+	return "", false
+}
+
+// End discards the game's state, profiles are retained.
+func (m *Model) End(gameKey string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.games, gameKey)
+}
+
+// Profile returns the profile of the snake by name.
+func (m *Model) Profile(name string) Profile {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.profiles[name]
+	if !ok {
+		return Profile{}
+	}
+	return *p
+}
+
+// Priors returns the move probabilities of the snake in the game in board.Moves order.
+// Prefer a snapshot of the game for repeated calls, see Game.
+func (m *Model) Priors(gameKey string, b *rules.BoardState, snakeIdx int) []float64 {
+	return m.Game(gameKey).Priors(b, snakeIdx, nil)
+}
+
+// Game returns a snapshot of the profiles of the game's snakes as a move prior policy,
+// e.g. once per search, later observations don't affect it.
+func (m *Model) Game(gameKey string) GamePolicy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	profiles := make(map[string]Profile)
+	if g, ok := m.games[gameKey]; ok {
+		for id, name := range g.names {
+			if p, ok := m.profiles[name]; ok {
+				profiles[id] = *p
+			}
+		}
+	}
+
+	return GamePolicy{profiles: profiles}
+}
+
+// GamePolicy provides the move priors of a game's snakes, see mcts.Policy. It is
+// safe for concurrent use.
+type GamePolicy struct {
+	profiles map[string]Profile // By snake ID
+}
+
+func (p GamePolicy) Priors(b *rules.BoardState, snakeIdx int, _ map[rules.Point]bool) []float64 {
+	return p.profiles[b.Snakes[snakeIdx].ID].Probs(b, snakeIdx)
+}
+
+// Save writes the profiles as JSON.
+func (m *Model) Save(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return json.NewEncoder(w).Encode(m.profiles)
+}
+
+// Load reads profiles as JSON, replacing existing profiles with the same names.
+func (m *Model) Load(r io.Reader) error {
+	profiles := make(map[string]*Profile)
+	if err := json.NewDecoder(r).Decode(&profiles); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, p := range profiles {
+		m.profiles[name] = p
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package opp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+// newBoard returns a board with snake "a" heading up at head and snake "b" in the corner.
+func newBoard(head rules.Point) *rules.BoardState {
+	return &rules.BoardState{
+		Height: 11,
+		Width:  11,
+		Food:   []rules.Point{{X: 8, Y: 5}},
+		Snakes: []rules.Snake{
+			{
+				ID:     "a",
+				Body:   []rules.Point{head, {X: head.X, Y: head.Y - 1}, {X: head.X, Y: head.Y - 2}},
+				Health: 100,
+			},
+			{
+				ID:     "b",
+				Body:   []rules.Point{{X: 0, Y: 10}, {X: 0, Y: 9}, {X: 0, Y: 8}},
+				Health: 100,
+			},
+		},
+	}
+}
+
+func TestModel(t *testing.T) {
+	names := map[string]string{"a": "hungry", "b": "other"}
+
+	m := NewModel()
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprint(i)
+		m.Observe(key, newBoard(rules.Point{X: 5, Y: 5}), names)
+
+		// Snake "a" moves right towards the food, not straight.
+		next := newBoard(rules.Point{X: 6, Y: 5})
+		next.Snakes[0].Body = []rules.Point{{X: 6, Y: 5}, {X: 5, Y: 5}, {X: 5, Y: 4}}
+		m.Observe(key, next, names)
+		m.End(key)
+	}
+
+	p := m.Profile("hungry")
+	require.Equal(t, 20, p.Moves)
+	require.InDelta(t, 21.0/22, p.Tendency(Food), 1e-9)
+	require.InDelta(t, 1.0/22, p.Tendency(Straight), 1e-9)
+	require.InDelta(t, 0.5, p.Tendency(H2H), 1e-9) // No choice
+
+	// Ended games use uniform priors, see board.Moves order.
+	b := newBoard(rules.Point{X: 5, Y: 5})
+	require.Equal(t, []float64{1.0 / 3, 0, 1.0 / 3, 1.0 / 3}, m.Priors("0", b, 0))
+
+	m.Observe("game", b, names)
+	snapshot := m.Game("game")
+	priors := snapshot.Priors(b, 0, nil)
+	require.Greater(t, priors[2], 0.9)
+	require.Equal(t, 0.0, priors[1])
+	require.InDelta(t, 1, priors[0]+priors[1]+priors[2]+priors[3], 1e-9)
+
+	// Profiles persist across models.
+	var buf bytes.Buffer
+	jtest.RequireNil(t, m.Save(&buf))
+
+	m2 := NewModel()
+	jtest.RequireNil(t, m2.Load(&buf))
+	require.Equal(t, p, m2.Profile("hungry"))
+
+	// Snapshots are unaffected by later changes of the profiles.
+	jtest.RequireNil(t, m.Load(strings.NewReader(`{"hungry":{}}`)))
+	require.Equal(t, priors, snapshot.Priors(b, 0, nil))
+	require.Equal(t, []float64{1.0 / 3, 0, 1.0 / 3, 1.0 / 3}, m.Priors("game", b, 0))
+}
+
+func TestFeatures(t *testing.T) {
+	b := newBoard(rules.Point{X: 5, Y: 5})
+	b.Snakes[1].Body = []rules.Point{{X: 6, Y: 6}, {X: 7, Y: 6}, {X: 8, Y: 6}}
+
+	feats, rational := features(b, 0)
+	require.Equal(t, [4]bool{true, false, true, true}, rational)
+
+	// Up: straight and next to "b", right: food and next to "b".
+	require.Equal(t, [numFeatures]bool{false, true, true}, feats[0])
+	require.Equal(t, [numFeatures]bool{true, true, false}, feats[2])
+	require.Equal(t, [numFeatures]bool{false, false, false}, feats[3])
+}
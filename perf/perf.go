@@ -2,190 +2,323 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
-	"math/rand"
+	"io"
 	"os"
-	"os/exec"
 	"os/signal"
+	"runtime"
 	"sort"
 	"strings"
 	"syscall"
 	"time"
 
-	"github.com/corverroos/bsrules/cli/commands"
+	"github.com/corverroos/bsnake/arena"
+	"github.com/corverroos/bsnake/tournament"
 )
 
+// config configures a perf run from flags, overridden by the config file if provided.
+// The "repro <game>" command replays a game of the results file instead and the
+// "tune" command tunes the options of a preset.
+type config struct {
+	Engines  []string `json:"engines"`   // Engine names, "ref:snake" engines are built from git refs.
+	Games    int      `json:"games"`     // Number of arena games.
+	Players  int      `json:"players"`   // Snakes per arena game.
+	Size     int      `json:"size"`      // Board width and height.
+	Types    []string `json:"types"`     // Game types played in turn.
+	Seed     int64    `json:"seed"`      // Seed of the first game.
+	Results  string   `json:"results"`   // File to append JSON game results to, resuming games already played.
+	MaxTurns int      `json:"max_turns"` // Maximum turns per game.
+	Workers  int      `json:"workers"`   // Arena games played concurrently.
+	Turn     int      `json:"turn"`      // First turn diagnosed when reproducing a game.
+
+	Mode    string  `json:"mode"`    // Tournament mode, empty plays arena games.
+	Rounds  int     `json:"rounds"`  // Maximum tournament rounds.
+	History string  `json:"history"` // Tournament history file.
+	SPRT    bool    `json:"sprt"`    // Stop a gauntlet once the candidate is decided.
+	Elo0    float64 `json:"elo0"`
+	Elo1    float64 `json:"elo1"`
+	Alpha   float64 `json:"alpha"`
+	Beta    float64 `json:"beta"`
+
+	Repo string `json:"repo"` // Git repository of ref engines.
+	Port int    `json:"port"` // First port of ref engine servers.
+
+	Base       string   `json:"base"`       // Tuned preset.
+	Params     []string `json:"params"`     // Tuned params, see tuner.DefaultParams.
+	Iterations int      `json:"iterations"` // Tuning iterations.
+	Batch      int      `json:"batch"`      // Games per tuning iteration.
+	Checkpoint string   `json:"checkpoint"` // Tuning checkpoint file.
+	Output     string   `json:"output"`     // Tuned options file, usable as a "*.json" engine.
+}
+
+func parseConfig() (config, error) {
+	var (
+		c          config
+		engines    = flag.String("engines", "mx4,mx2", "comma separated engine names, see arena.Engines; ref:snake engines are built from git refs, e.g. main:v5; *.json engines are mcts options files")
+		params     = flag.String("params", "", "comma separated tuned params, default all")
+		types      = flag.String("types", "standard,royale", "comma separated game types played in turn")
+		configFile = flag.String("config", "", "JSON config file overriding flags")
+	)
+	flag.IntVar(&c.Games, "games", 50, "number of games")
+	flag.IntVar(&c.Players, "players", 3, "snakes per game")
+	flag.IntVar(&c.Size, "size", 11, "board width and height")
+	flag.Int64Var(&c.Seed, "seed", 0, "seed of the first game")
+	flag.StringVar(&c.Results, "results", "", "file to append JSON game results to, resuming games already played")
+	flag.IntVar(&c.MaxTurns, "max_turns", 1000, "maximum turns per game")
+	flag.IntVar(&c.Workers, "workers", runtime.NumCPU(), "arena games played concurrently")
+	flag.IntVar(&c.Turn, "turn", 0, "first turn diagnosed when reproducing a game")
+	flag.StringVar(&c.Mode, "mode", "", "tournament mode; roundrobin or gauntlet (candidate first), default plays arena games")
+	flag.IntVar(&c.Rounds, "rounds", 100, "maximum tournament rounds")
+	flag.StringVar(&c.History, "history", "tournament.json", "tournament history file")
+	flag.BoolVar(&c.SPRT, "sprt", false, "stop a gauntlet once the candidate is decided better or not")
+	flag.Float64Var(&c.Elo0, "elo0", 0, "sprt null hypothesis elo")
+	flag.Float64Var(&c.Elo1, "elo1", 20, "sprt alternative hypothesis elo")
+	flag.Float64Var(&c.Alpha, "alpha", 0.05, "sprt false positive rate")
+	flag.Float64Var(&c.Beta, "beta", 0.05, "sprt false negative rate")
+	flag.StringVar(&c.Repo, "repo", ".", "git repository of ref engines")
+	flag.IntVar(&c.Port, "port", 8090, "first port of ref engine servers")
+	flag.StringVar(&c.Base, "base", "v5", "tuned preset, v1 to v5 or the minimax fmx0 to fmx3")
+	flag.IntVar(&c.Iterations, "iterations", 100, "tuning iterations")
+	flag.IntVar(&c.Batch, "batch", 2, "games per tuning iteration")
+	flag.StringVar(&c.Checkpoint, "checkpoint", "tune.json", "tuning checkpoint file")
+	flag.StringVar(&c.Output, "output", "tuned.json", "tuned options file")
+	flag.Parse()
+
+	c.Engines = strings.Split(*engines, ",")
+	c.Types = strings.Split(*types, ",")
+	if *params != "" {
+		c.Params = strings.Split(*params, ",")
+	}
+
+	if *configFile == "" {
+		return c, nil
+	}
+
+	b, err := os.ReadFile(*configFile)
+	if err != nil {
+		return config{}, err
+	}
+
+	if err := json.Unmarshal(b, &c); err != nil {
+		return config{}, fmt.Errorf("config: %w", err)
+	}
+
+	return c, nil
+}
+
 func main() {
+	c, err := parseConfig()
+	if err != nil {
+		fmt.Printf("Error: %v", err)
+		os.Exit(2)
+	}
+
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT)
 	defer cancel()
 
-	sl := []server{
-		{
-			Port: 8082,
-			//Snakes: []string{"v4", "v5", "mx2", "mx3", "mx4", "mx5"},
-			Snakes: []string{"mx4", "mx2"},
-		},
-		//{
-		//	Ref:    "e9de2a3c67548abc89fb59cea871ec02069ba26b",
-		//	Port:   8083,
-		//	Snakes: []string{"boomboom"},
-		//},
+	switch flag.Arg(0) {
+	case "repro":
+		err = repro(ctx, c, flag.Arg(1))
+	case "tune":
+		err = tune(ctx, c, arenaOptions(c))
+	default:
+		err = run(ctx, c)
 	}
+	if err != nil {
+		fmt.Printf("Error: %v", err)
+		os.Exit(3)
+	}
+}
 
-	opts := options{
-		Total:   50,
-		Players: 3,
+func run(ctx context.Context, c config) error {
+	if err := registerConfigs(c.Engines); err != nil {
+		return err
 	}
 
-	res, err := run(ctx, opts, sl...)
+	servers, err := startRefs(ctx, c.Repo, c.Port, c.Engines)
 	if err != nil {
-		fmt.Printf("Error: %v", err)
-		os.Exit(3)
+		return err
+	}
+	defer servers.stop()
+
+	opts := arenaOptions(c)
+
+	if c.Mode != "" {
+		return runTournament(ctx, c, opts)
+	}
+
+	res, err := runArena(ctx, c, opts)
+	if err != nil {
+		return err
 	}
 	fmt.Println(res)
-}
 
-type options struct {
-	Total   int
-	Players int
+	return nil
 }
 
-type server struct {
-	Ref    string
-	Port   int
-	Snakes []string
+// arenaOptions returns the arena options of the config.
+func arenaOptions(c config) arena.Options {
+	return arena.Options{
+		Engines:   c.Engines,
+		Players:   c.Players,
+		Games:     c.Games,
+		Width:     int32(c.Size),
+		Height:    int32(c.Size),
+		GameTypes: c.Types,
+		Seed:      c.Seed,
+		MaxTurns:  c.MaxTurns,
+		Workers:   c.Workers,
+	}
 }
 
 type result struct {
-	Wins map[string]int
+	Plays  map[string]int
+	Wins   map[string]int
+	Draws  map[string]int
+	Errors int
 }
 
-func run(ctx context.Context, opt options, sl ...server) (result, error) {
-	exec.Command("pkill", "bsnake").Run()
+func (r result) String() string {
+	var msg []string
+	for s, p := range r.Plays {
+		msg = append(msg, fmt.Sprintf("%s p=%d\tw=%d\td=%d", s, p, r.Wins[s], r.Draws[s]))
+	}
+	sort.Strings(msg)
+
+	return fmt.Sprintf("Results [errors=%d]\n\t%s", r.Errors, strings.Join(msg, "\n\t"))
+}
+
+func (r *result) add(g arena.Result) {
+	if g.Error != "" {
+		r.Errors++
+	}
+
+	for _, name := range g.Engines {
+		r.Plays[name]++
+		if g.Winner == -1 {
+			r.Draws[name]++
+		}
+	}
+	if g.Winner != -1 {
+		r.Wins[g.WinnerName()]++
+	}
+}
 
-	for i, s := range sl {
-		branch := "main"
-		if len(sl) > 1 {
-			if s.Ref != "" {
-				branch = s.Ref
-			}
+func runArena(ctx context.Context, c config, opts arena.Options) (result, error) {
+	res := result{
+		Plays: make(map[string]int),
+		Wins:  make(map[string]int),
+		Draws: make(map[string]int),
+	}
 
-			out, err := exec.Command("git", "checkout", branch).CombinedOutput()
-			if err != nil {
-				return result{}, fmt.Errorf("gco %v: %s", err, out)
-			}
+	enc := json.NewEncoder(os.Stdout)
+	if c.Results != "" {
+		played, err := loadResults(c.Results)
+		if err != nil {
+			return result{}, err
+		}
+
+		opts.Skip = make(map[int64]bool)
+		for _, r := range played {
+			opts.Skip[r.Seed] = true
+			res.add(r)
+		}
+		if len(played) > 0 {
+			fmt.Printf("Resuming %s [played=%d]\n", c.Results, len(played))
 		}
 
-		cmd := exec.CommandContext(ctx, "go", "run", "github.com/corverroos/bsnake")
-		cmd.Dir = "/Users/corver/core/repos/bsnake"
-		cmd.Env = append([]string{fmt.Sprintf("BIND=localhost:%d", s.Port)}, os.Environ()...)
-		go func(cmd *exec.Cmd) {
-			fmt.Printf("Starting server %s on branch %v\n", cmd.String(), branch)
-			out, err := cmd.CombinedOutput()
-			if ctx.Err() == nil && err != nil {
-				fmt.Printf("server error %d: %s", i, out)
-			}
-		}(cmd)
-
-		time.Sleep(time.Second)
-
-		if len(sl) > 1 {
-			out, err := exec.Command("git", "checkout", "main").CombinedOutput()
-			if err != nil {
-				return result{}, fmt.Errorf("gco %v: %s", err, out)
-			}
+		f, err := os.OpenFile(c.Results, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return result{}, err
 		}
+		defer f.Close()
+		enc = json.NewEncoder(f)
 	}
 
-	wins := make(map[string]map[string]int)
-	losses := make(map[string]map[string]int)
-	plays := make(map[string]int)
-	draws := make(map[string]int)
-	for i := 0; i < opt.Total; i++ {
-		if ctx.Err() != nil {
+	t0 := time.Now()
+	total := opts.Games - len(opts.Skip)
+	var done int
+
+	err := arena.Run(ctx, opts, func(r arena.Result) error {
+		done++
+		res.add(r)
+		if r.Error != "" {
+			fmt.Printf("Game %s error: %s\n", r.ID, r.Error)
+		}
+
+		eta := time.Since(t0) / time.Duration(done) * time.Duration(total-done)
+		fmt.Printf("Game %s [%d/%d, winner=%s, turns=%d, type=%s, duration=%.0fs, eta=%s]\n",
+			r.ID, done, total, r.WinnerName(), r.Turns, r.Type, r.Duration.Seconds(), eta.Round(time.Second))
+
+		return enc.Encode(r)
+	})
+	if err != nil && ctx.Err() == nil {
+		return result{}, err
+	}
+
+	return res, nil
+}
+
+// loadResults returns the JSON game results in the file, ignoring a truncated last line.
+func loadResults(path string) ([]arena.Result, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var res []arena.Result
+	dec := json.NewDecoder(f)
+	for {
+		var r arena.Result
+		if err := dec.Decode(&r); err == io.EOF || err == io.ErrUnexpectedEOF {
 			break
+		} else if err != nil {
+			return nil, fmt.Errorf("results %s: %w", path, err)
 		}
+		res = append(res, r)
+	}
 
-		func(i int) {
-			var logs []string
-			defer func() {
-				if r := recover(); r != nil {
-					fmt.Printf("Panic: %v", r)
-					fmt.Print(strings.Join(logs, "\n"))
-				}
-			}()
-
-			game := []string{"standard", "royale"}[i%2]
-
-			o := &commands.Options{
-				Width:    11,
-				Height:   11,
-				GameType: game,
-				Seed:     int64(i),
-				Log: func(s string, i ...interface{}) {
-					logs = append(logs, fmt.Sprintf(s, i...))
-				},
-			}
-
-			for _, s := range sl {
-				for _, snake := range s.Snakes {
-					o.Names = append(o.Names, nameSnake(s, len(sl), snake))
-					o.URLs = append(o.URLs, fmt.Sprintf("http://localhost:%d/%s/", s.Port, snake))
-				}
-			}
-
-			for opt.Players > 0 && len(o.Names) > opt.Players {
-				drop := rand.Intn(len(o.Names))
-				o.Names = append(o.Names[0:drop], o.Names[drop+1:]...)
-				o.URLs = append(o.URLs[0:drop], o.URLs[drop+1:]...)
-			}
-
-			t0 := time.Now()
-			res := commands.Run(o)
-
-			for snake, info := range res.Infos {
-				if _, ok := plays[snake]; !ok {
-					wins[snake] = make(map[string]int)
-					losses[snake] = make(map[string]int)
-					fmt.Printf("Infos %s: %v\n", snake, info)
-				}
-				plays[snake]++
-
-				if res.Winner == "" {
-					draws[snake]++
-				} else {
-					for other := range res.Infos {
-						if other == snake && snake == res.Winner {
-							wins[snake]["total"]++
-						} else if other == snake && snake != res.Winner {
-							losses[snake]["total"]++
-						} else if snake == res.Winner {
-							wins[snake][other]++
-						} else if other == res.Winner {
-							losses[snake][other]++
-						}
-					}
-				}
-			}
-
-			var msg []string
-			for s, p := range plays {
-				msg = append(msg, fmt.Sprintf("%s p=%d\tw=%v\tl=%v", s, p, wins[s], losses[s]))
-			}
-			sort.Strings(msg)
-
-			fmt.Printf("Results\t[winner=%s, turns=%d, game=%s, duration=%.0fs]\n\t%s\n", res.Winner, res.Turn, game, time.Since(t0).Seconds(), strings.Join(msg, "\n\t"))
-		}(i)
-	}
-
-	return result{
-		//Wins: wins,
-	}, nil
+	return res, nil
 }
 
-func nameSnake(srv server, sl int, path string) string {
-	if sl == 1 || srv.Ref == "" {
-		return path
+func runTournament(ctx context.Context, c config, opts arena.Options) error {
+	o := tournament.Options{
+		Mode:    tournament.Mode(c.Mode),
+		Engines: opts.Engines,
+		Rounds:  c.Rounds,
+		Arena:   opts,
+		Path:    c.History,
 	}
-	return fmt.Sprintf("%s_%s", srv.Ref[:1], path)
+	if c.SPRT {
+		o.SPRT = &tournament.SPRT{Elo0: c.Elo0, Elo1: c.Elo1, Alpha: c.Alpha, Beta: c.Beta}
+	}
+
+	res, err := tournament.Run(ctx, o, func(r arena.Result) {
+		fmt.Printf("Game %s [engines=%v, winner=%s, turns=%d, type=%s, duration=%.0fs]\n",
+			r.ID, r.Engines, r.WinnerName(), r.Turns, r.Type, r.Duration.Seconds())
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Ratings [games=%d]\n", res.Games)
+	for _, r := range res.Ratings {
+		fmt.Printf("\t%s\t%+.0f ± %.0f\t(%d games)\n", r.Name, r.Elo, r.CI, r.Games)
+	}
+
+	if o.Mode == tournament.Gauntlet {
+		elo, ci := res.Score.Elo()
+		fmt.Printf("Candidate %s [w=%d d=%d l=%d elo=%+.0f ± %.0f]\n", o.Engines[0], res.Score.W, res.Score.D, res.Score.L, elo, ci)
+	}
+	if o.SPRT != nil {
+		lower, upper := o.SPRT.Bounds()
+		fmt.Printf("SPRT [decision=%s llr=%.2f (%.2f, %.2f)]\n", res.Decision, res.LLR, lower, upper)
+	}
+
+	return nil
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/corverroos/bsnake/arena"
+)
+
+// servers are the snake server processes started for git ref engines.
+type servers struct {
+	dir    string
+	cancel context.CancelFunc
+	cmds   []*exec.Cmd
+}
+
+// stop kills the started processes and removes the built binaries.
+func (s *servers) stop() {
+	s.cancel()
+	for _, cmd := range s.cmds {
+		_ = cmd.Wait()
+	}
+	_ = os.RemoveAll(s.dir)
+}
+
+// startRefs builds each git ref of the "ref:snake" engines from a temporary worktree of
+// the repo, serves each on its own port from the first port and registers its engines.
+func startRefs(ctx context.Context, repo string, port int, engines []string) (*servers, error) {
+	snakes := make(map[string][]string)
+	var refs []string
+	for _, name := range engines {
+		i := strings.LastIndex(name, ":")
+		if i < 0 {
+			continue
+		}
+		ref := name[:i]
+		if _, ok := snakes[ref]; !ok {
+			refs = append(refs, ref)
+		}
+		snakes[ref] = append(snakes[ref], name[i+1:])
+	}
+
+	dir, err := os.MkdirTemp("", "bsnake-perf-")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	res := &servers{dir: dir, cancel: cancel}
+
+	for i, ref := range refs {
+		bin, err := buildRef(ctx, repo, ref, filepath.Join(dir, fmt.Sprint(i)))
+		if err != nil {
+			res.stop()
+			return nil, err
+		}
+
+		addr := fmt.Sprintf("localhost:%d", port+i)
+		cmd := exec.CommandContext(ctx, bin)
+		cmd.Env = append(os.Environ(), "BIND="+addr)
+		if err := cmd.Start(); err != nil {
+			res.stop()
+			return nil, err
+		}
+		res.cmds = append(res.cmds, cmd)
+		fmt.Printf("Started %s on %s [pid=%d]\n", ref, addr, cmd.Process.Pid)
+
+		for _, snake := range snakes[ref] {
+			url := fmt.Sprintf("http://%s/%s/", addr, snake)
+			if err := awaitServer(ctx, url); err != nil {
+				res.stop()
+				return nil, fmt.Errorf("%s:%s: %w", ref, snake, err)
+			}
+			arena.Register(ref+":"+snake, arena.HTTPEngine(url, time.Second))
+		}
+	}
+
+	return res, nil
+}
+
+// buildRef builds the binary of the git ref from a temporary worktree in dir and returns its path.
+func buildRef(ctx context.Context, repo, ref, dir string) (string, error) {
+	tree := filepath.Join(dir, "src")
+	bin := filepath.Join(dir, "bsnake")
+
+	out, err := exec.CommandContext(ctx, "git", "-C", repo, "worktree", "add", "--detach", tree, ref).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("worktree %s: %v: %s", ref, err, out)
+	}
+	defer func() {
+		_ = exec.Command("git", "-C", repo, "worktree", "remove", "--force", tree).Run()
+	}()
+
+	build := exec.CommandContext(ctx, "go", "build", "-o", bin, ".")
+	build.Dir = tree
+	if out, err := build.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("build %s: %v: %s", ref, err, out)
+	}
+
+	return bin, nil
+}
+
+// awaitServer polls the snake info url until it responds or times out.
+func awaitServer(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("server not ready: %w", ctx.Err())
+		case <-time.After(time.Millisecond * 100):
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/corverroos/bsnake/arena"
+	"github.com/corverroos/bsnake/board"
+)
+
+// repro replays the game of the results file turn for turn, printing the board, the
+// recorded and replayed move of each snake and the engines' search diagnostics.
+func repro(ctx context.Context, c config, id string) error {
+	if c.Results == "" || id == "" {
+		return fmt.Errorf("usage: perf -results <file> [-turn <turn>] repro <game>")
+	}
+
+	results, err := loadResults(c.Results)
+	if err != nil {
+		return err
+	}
+
+	var (
+		r     arena.Result
+		found bool
+	)
+	for _, res := range results {
+		if res.ID == id {
+			r, found = res, true
+		}
+	}
+	if !found {
+		return fmt.Errorf("game %s not found in %s", id, c.Results)
+	}
+
+	if err := registerConfigs(r.Engines); err != nil {
+		return err
+	}
+
+	servers, err := startRefs(ctx, c.Repo, c.Port, r.Engines)
+	if err != nil {
+		return err
+	}
+	defer servers.stop()
+
+	fmt.Printf("Game %s [type=%s, seed=%d, engines=%v, seeds=%v, winner=%s, turns=%d]\n",
+		r.ID, r.Type, r.Seed, r.Engines, r.Seeds, r.WinnerName(), r.Turns)
+
+	// Buffer each move's diagnostics to print them after the board.
+	var logs []string
+	logf := func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+
+	turn := -1
+	err = arena.Replay(ctx, r, c.Turn, logf, func(rep arena.Replayed) error {
+		if rep.Turn.Turn != turn {
+			turn = rep.Turn.Turn
+			fmt.Printf("\nTurn %d [moves=%s]\n", turn, r.Moves[turn])
+			fmt.Println(board.PrintBoard(rep.Turn.Board, rep.Turn.Hazards))
+		}
+
+		var diff string
+		if rep.Move != rep.Recorded {
+			diff = " *"
+		}
+		fmt.Printf("Snake %d %s [recorded=%s, replayed=%s, duration=%dms]%s\n", rep.Turn.SnakeIdx,
+			r.Engines[rep.Turn.SnakeIdx], rep.Recorded, rep.Move, rep.Duration.Milliseconds(), diff)
+		if rep.Err != nil {
+			fmt.Printf("\terror: %v\n", rep.Err)
+		}
+		for _, l := range logs {
+			fmt.Printf("\t%s\n", l)
+		}
+		logs = nil
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nResult [winner=%s, deaths=%v, causes=%v]\n", r.WinnerName(), r.Deaths, r.Causes)
+
+	return nil
+}
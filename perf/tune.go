@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/corverroos/bsnake/arena"
+	"github.com/corverroos/bsnake/mcts"
+	"github.com/corverroos/bsnake/tuner"
+)
+
+// presets are the options tuned from by name, the minimax presets are tuned with
+// alpha-beta engines, see mcts.Opts.IsMinimax.
+var presets = map[string]mcts.Opts{
+	"v1":   mcts.OptsV1,
+	"v2":   mcts.OptsV2,
+	"v3":   mcts.OptsV3,
+	"v4":   mcts.OptsV4,
+	"v5":   mcts.OptsV5,
+	"fmx0": mcts.OptsMx0,
+	"fmx1": mcts.OptsMx1,
+	"fmx2": mcts.OptsMx2,
+	"fmx3": mcts.OptsMx3,
+}
+
+// tune tunes the params of the base preset, writing the tuned options to the output file.
+func tune(ctx context.Context, c config, opts arena.Options) error {
+	base, ok := presets[c.Base]
+	if !ok {
+		return fmt.Errorf("unknown base preset: %s", c.Base)
+	}
+
+	var params []tuner.Param
+	if len(c.Params) > 0 {
+		var err error
+		params, err = tuner.ParamsByName(c.Params)
+		if err != nil {
+			return err
+		}
+	} else if base.IsMinimax() {
+		// Minimax only uses the heuristic factors.
+		for _, p := range tuner.DefaultParams {
+			if strings.HasPrefix(p.Name, "HeurFactors.") {
+				params = append(params, p)
+			}
+		}
+	}
+
+	o := tuner.Options{
+		Base:       base,
+		Params:     params,
+		Iterations: c.Iterations,
+		Games:      c.Batch,
+		Arena:      opts,
+		Path:       c.Checkpoint,
+		Engine:     engine(base),
+	}
+
+	res, err := tuner.Run(ctx, o, func(cp tuner.Checkpoint) {
+		fmt.Printf("Iteration %d [score=%+.2f, values=%v]\n", cp.Iteration, cp.Scores[len(cp.Scores)-1], cp.Values)
+	})
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(res, "", " ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Tuned %s [output=%s]\n%s\n", c.Base, c.Output, b)
+
+	return os.WriteFile(c.Output, b, 0o644)
+}
+
+// engine returns the arena engine constructor of the options.
+func engine(o mcts.Opts) func(mcts.Opts) func(*rand.Rand) arena.Engine {
+	if o.IsMinimax() {
+		return arena.MinimaxEngine
+	}
+	return arena.MCTSEngine
+}
+
+// registerConfigs registers the "*.json" engines as MCTS or minimax engines of the options
+// in the files, e.g. the tuned options.
+func registerConfigs(engines []string) error {
+	for _, name := range engines {
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		var o mcts.Opts
+		if err := json.Unmarshal(b, &o); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		arena.Register(name, engine(o)(o))
+	}
+
+	return nil
+}
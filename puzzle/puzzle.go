@@ -0,0 +1,260 @@
+// Package puzzle benchmarks arena engines on board positions with accepted and rejected moves.
+package puzzle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/corverroos/bsnake/arena"
+	"github.com/corverroos/bsnake/boardtxt"
+)
+
+// MustPass tags puzzles that the must engines of the bench command, v5 and mx5 by
+// default, must pass.
+const MustPass = "must-pass"
+
+// Puzzle is a board position of a snake and the moves expected of it.
+type Puzzle struct {
+	Name     string   `json:"name"`
+	Board    string   `json:"board"`     // Battlesnake API request or ".board.txt" file, relative to the suite file.
+	Accept   []string `json:"accept"`    // Accepted moves, any move not rejected if empty.
+	Reject   []string `json:"reject"`    // Rejected moves, e.g. deadly moves.
+	Tags     []string `json:"tags"`      // Sources and themes of the puzzle, see MustPass.
+	BudgetMS int      `json:"budget_ms"` // Time budget of a move, defaults to 500ms.
+
+	req []byte // Battlesnake API request
+}
+
+// Load returns the puzzles of the JSON suite file including their boards.
+func Load(path string) ([]Puzzle, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Puzzle
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, fmt.Errorf("suite %s: %w", path, err)
+	}
+
+	for i, p := range res {
+		res[i].req, err = readBoard(filepath.Join(filepath.Dir(path), p.Board))
+		if err != nil {
+			return nil, fmt.Errorf("puzzle %s: %w", p.Name, err)
+		}
+
+		if _, err := res[i].turn(); err != nil {
+			return nil, fmt.Errorf("puzzle %s: %w", p.Name, err)
+		}
+	}
+
+	return res, nil
+}
+
+// readBoard returns the Battlesnake API request of the board file, converting text boards.
+func readBoard(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(path, ".txt") {
+		return b, nil
+	}
+
+	g, err := boardtxt.Parse(string(b))
+	if err != nil {
+		return nil, err
+	}
+
+	return g.Request()
+}
+
+// HasTag returns true if the puzzle has the tag.
+func (p Puzzle) HasTag(tag string) bool {
+	for _, t := range p.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Budget returns the time budget of a move.
+func (p Puzzle) Budget() time.Duration {
+	if p.BudgetMS == 0 {
+		return time.Millisecond * 500
+	}
+	return time.Millisecond * time.Duration(p.BudgetMS)
+}
+
+// Check returns true if the move is accepted and not rejected.
+func (p Puzzle) Check(move string) bool {
+	for _, m := range p.Reject {
+		if m == move {
+			return false
+		}
+	}
+
+	if len(p.Accept) == 0 {
+		return true
+	}
+
+	for _, m := range p.Accept {
+		if m == move {
+			return true
+		}
+	}
+
+	return false
+}
+
+// turn returns a new arena turn of the puzzle's board.
+func (p Puzzle) turn() (arena.Turn, error) {
+	g, err := boardtxt.FromRequest(p.req)
+	if err != nil {
+		return arena.Turn{}, err
+	}
+
+	t := arena.Turn{
+		GameID:   g.ID,
+		Turn:     g.Turn,
+		Board:    g.Board,
+		SnakeIdx: g.You,
+		Hazards:  g.Hazards,
+		Names:    make(map[string]string),
+	}
+	for _, s := range g.Board.Snakes {
+		t.Names[s.ID] = s.ID
+	}
+
+	return t, nil
+}
+
+// Matrix is the number of passes of each engine on each puzzle.
+type Matrix struct {
+	Engines []string
+	Puzzles []Puzzle
+	Runs    int     // Runs of each engine on each puzzle
+	Passes  [][]int // Passes by puzzle and engine index
+}
+
+// Run runs each engine on each puzzle the number of runs and returns the pass matrix.
+// A run passes if the move is accepted within the puzzle's time budget, which is also the
+// deadline of the engine's context.
+func Run(ctx context.Context, engines []string, puzzles []Puzzle, runs int) (Matrix, error) {
+	if runs == 0 {
+		runs = 1
+	}
+
+	m := Matrix{
+		Engines: engines,
+		Puzzles: puzzles,
+		Runs:    runs,
+		Passes:  make([][]int, len(puzzles)),
+	}
+
+	for i, p := range puzzles {
+		m.Passes[i] = make([]int, len(engines))
+		for j, name := range engines {
+			for run := 0; run < runs; run++ {
+				if ctx.Err() != nil {
+					return Matrix{}, ctx.Err()
+				}
+
+				e, err := arena.NewEngine(name, int64(run))
+				if err != nil {
+					return Matrix{}, err
+				}
+
+				t, err := p.turn()
+				if err != nil {
+					return Matrix{}, err
+				}
+
+				t0 := time.Now()
+				mctx, cancel := context.WithTimeout(ctx, p.Budget())
+				move, err := e.Move(mctx, t)
+				cancel()
+				if ctx.Err() != nil {
+					return Matrix{}, ctx.Err()
+				}
+				if err == nil && time.Since(t0) <= p.Budget() && p.Check(move) {
+					m.Passes[i][j]++
+				}
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// Failed returns the "puzzle/engine" pairs of puzzles with the tag that didn't pass every
+// run of the engines, all engines if nil.
+func (m Matrix) Failed(tag string, engines []string) []string {
+	include := func(name string) bool {
+		if engines == nil {
+			return true
+		}
+		for _, e := range engines {
+			if e == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	var res []string
+	for i, p := range m.Puzzles {
+		if !p.HasTag(tag) {
+			continue
+		}
+		for j, name := range m.Engines {
+			if include(name) && m.Passes[i][j] < m.Runs {
+				res = append(res, p.Name+"/"+name)
+			}
+		}
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// String returns the aligned pass rate table of the puzzles by engine and the total by engine.
+func (m Matrix) String() string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+
+	fmt.Fprint(w, "puzzle")
+	for _, name := range m.Engines {
+		fmt.Fprintf(w, "\t%s", name)
+	}
+	fmt.Fprint(w, "\ttags\n")
+
+	totals := make([]int, len(m.Engines))
+	for i, p := range m.Puzzles {
+		fmt.Fprint(w, p.Name)
+		for j := range m.Engines {
+			fmt.Fprintf(w, "\t%.0f%%", 100*float64(m.Passes[i][j])/float64(m.Runs))
+			totals[j] += m.Passes[i][j]
+		}
+		fmt.Fprintf(w, "\t%s\n", strings.Join(p.Tags, ","))
+	}
+
+	fmt.Fprint(w, "total")
+	for j := range m.Engines {
+		fmt.Fprintf(w, "\t%.0f%%", 100*float64(totals[j])/float64(m.Runs*len(m.Puzzles)))
+	}
+	fmt.Fprint(w, "\t\n")
+
+	_ = w.Flush()
+
+	return sb.String()
+}
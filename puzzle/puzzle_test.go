@@ -0,0 +1,104 @@
+package puzzle
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/corverroos/bsnake/arena"
+)
+
+func TestCheck(t *testing.T) {
+	p := Puzzle{Accept: []string{"up", "left"}, Reject: []string{"down"}}
+	require.True(t, p.Check("up"))
+	require.True(t, p.Check("left"))
+	require.False(t, p.Check("down"))
+	require.False(t, p.Check("right"))
+
+	p = Puzzle{Reject: []string{"down"}}
+	require.True(t, p.Check("right"))
+	require.False(t, p.Check("down"))
+}
+
+// TestMustPass runs the must engines of the bench command on the must-pass puzzles.
+func TestMustPass(t *testing.T) {
+	if testing.Short() {
+		t.Skip("searches take long")
+	}
+
+	puzzles, err := Load("../testdata/puzzles.json")
+	jtest.RequireNil(t, err)
+
+	var must []Puzzle
+	for _, p := range puzzles {
+		if p.HasTag(MustPass) {
+			must = append(must, p)
+		}
+	}
+	require.NotEmpty(t, must)
+
+	m, err := Run(context.Background(), []string{"v5", "mx5"}, must, 1)
+	jtest.RequireNil(t, err)
+	require.Empty(t, m.Failed(MustPass, nil), m.String())
+}
+
+func TestFailed(t *testing.T) {
+	m := Matrix{
+		Engines: []string{"a", "b"},
+		Puzzles: []Puzzle{
+			{Name: "p0", Tags: []string{MustPass}},
+			{Name: "p1"},
+			{Name: "p2", Tags: []string{"other", MustPass}},
+		},
+		Runs:   2,
+		Passes: [][]int{{2, 1}, {0, 0}, {2, 2}},
+	}
+	require.Equal(t, []string{"p0/b"}, m.Failed(MustPass, nil))
+	require.Empty(t, m.Failed(MustPass, []string{"a"}))
+	require.Empty(t, m.Failed("other", nil))
+}
+
+func TestLoadText(t *testing.T) {
+	dir := t.TempDir()
+	suite := filepath.Join(dir, "suite.json")
+	jtest.RequireNil(t, os.WriteFile(suite, []byte(`[{"name":"corner","board":"corner.board.txt","reject":["up","left"]}]`), 0o644))
+	jtest.RequireNil(t, os.WriteFile(filepath.Join(dir, "corner.board.txt"), []byte("turn: 3\n.*.\ny..\nY..\n"), 0o644))
+
+	puzzles, err := Load(suite)
+	jtest.RequireNil(t, err)
+	require.Len(t, puzzles, 1)
+
+	turn, err := puzzles[0].turn()
+	jtest.RequireNil(t, err)
+	require.Equal(t, 3, turn.Turn)
+	require.Equal(t, 0, turn.SnakeIdx)
+	require.Len(t, turn.Board.Snakes[0].Body, 2)
+	require.Len(t, turn.Board.Food, 1)
+}
+
+func TestRunBudget(t *testing.T) {
+	var budgets []time.Duration
+	arena.Register("puzzle-deadline", func(*rand.Rand) arena.Engine {
+		return arena.EngineFunc(func(ctx context.Context, _ arena.Turn) (string, error) {
+			deadline, ok := ctx.Deadline()
+			require.True(t, ok)
+			budgets = append(budgets, time.Until(deadline).Round(time.Millisecond*50))
+			return "up", nil
+		})
+	})
+
+	req, err := readBoard("../testdata/input-001.json")
+	jtest.RequireNil(t, err)
+
+	puzzles := []Puzzle{{Name: "default", req: req}, {Name: "long", req: req, BudgetMS: 2000}}
+	m, err := Run(context.Background(), []string{"puzzle-deadline"}, puzzles, 1)
+	jtest.RequireNil(t, err)
+	require.Equal(t, [][]int{{1}, {1}}, m.Passes)
+	require.Equal(t, []time.Duration{time.Millisecond * 500, time.Second * 2}, budgets)
+}
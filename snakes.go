@@ -2,11 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/BattlesnakeOfficial/rules"
 
-	"github.com/corverroos/bsnake/heur"
 	"github.com/corverroos/bsnake/mcts"
+	"github.com/corverroos/bsnake/opp"
 )
 
 type snake struct {
@@ -35,25 +44,27 @@ var snakes = map[string]snake{
 		},
 	},
 	"mx0": {
-		Description: "Minimax 2-ply",
+		Description: "Minimax alpha-beta, iterative deepening",
 		Info: BattlesnakeInfoResponse{
 			APIVersion: "1",
-			Meta:       fmx0,
+			Meta:       mcts.OptsMx0.HeurFactors,
 		},
 		Move: func(ctx context.Context, req GameRequest) (string, error) {
 			board, rootIdx := gameReqToBoard(req)
-			return mcts.SelectMinimax(board, coordsToPoints(req.Board.Hazards), rootIdx, &fmx0, mxDepth(board))
+			o := mcts.OptsMx0
+			return mcts.SelectMinimax(ctx, board, coordsToPoints(req.Board.Hazards), rootIdx, &o)
 		},
 	},
 	"mx1": {
-		Description: "Minimax 4-ply",
+		Description: "Minimax alpha-beta, iterative deepening",
 		Info: BattlesnakeInfoResponse{
 			APIVersion: "1",
-			Meta:       fmx1,
+			Meta:       mcts.OptsMx1.HeurFactors,
 		},
 		Move: func(ctx context.Context, req GameRequest) (string, error) {
 			board, rootIdx := gameReqToBoard(req)
-			return mcts.SelectMinimax(board, coordsToPoints(req.Board.Hazards), rootIdx, &fmx1, mxDepth(board))
+			o := mcts.OptsMx1
+			return mcts.SelectMinimax(ctx, board, coordsToPoints(req.Board.Hazards), rootIdx, &o)
 		},
 	},
 	"mx2": {
@@ -67,7 +78,7 @@ var snakes = map[string]snake{
 		},
 		Move: func(ctx context.Context, req GameRequest) (string, error) {
 			board, rootIdx := gameReqToBoard(req)
-			return mcts.SelectMx(board, coordsToPoints(req.Board.Hazards), rootIdx, &mcts.OptsV4)
+			return mcts.SelectMx(ctx, board, coordsToPoints(req.Board.Hazards), rootIdx, &mcts.OptsV4)
 		},
 	},
 	"mx3": {
@@ -81,7 +92,7 @@ var snakes = map[string]snake{
 		},
 		Move: func(ctx context.Context, req GameRequest) (string, error) {
 			board, rootIdx := gameReqToBoard(req)
-			return mcts.SelectMx(board, coordsToPoints(req.Board.Hazards), rootIdx, &mcts.OptsV3)
+			return mcts.SelectMx(ctx, board, coordsToPoints(req.Board.Hazards), rootIdx, &mcts.OptsV3)
 		},
 	},
 	"mx4": {
@@ -95,7 +106,7 @@ var snakes = map[string]snake{
 		},
 		Move: func(ctx context.Context, req GameRequest) (string, error) {
 			board, rootIdx := gameReqToBoard(req)
-			return mcts.SelectMx(board, coordsToPoints(req.Board.Hazards), rootIdx, &mcts.OptsV2)
+			return mcts.SelectMx(ctx, board, coordsToPoints(req.Board.Hazards), rootIdx, &mcts.OptsV2)
 		},
 	},
 	"mx5": {
@@ -109,7 +120,7 @@ var snakes = map[string]snake{
 		},
 		Move: func(ctx context.Context, req GameRequest) (string, error) {
 			board, rootIdx := gameReqToBoard(req)
-			return mcts.SelectMx(board, coordsToPoints(req.Board.Hazards), rootIdx, &mcts.OptsV5)
+			return mcts.SelectMx(ctx, board, coordsToPoints(req.Board.Hazards), rootIdx, &mcts.OptsV5)
 		},
 	},
 	"v1": {
@@ -187,37 +198,283 @@ var snakes = map[string]snake{
 		},
 		Move: func(ctx context.Context, req GameRequest) (string, error) {
 			board, rootIdx := gameReqToBoard(req)
-			return mcts.SelectMove(ctx, board, coordsToPoints(req.Board.Hazards), rootIdx, &mcts.OptsV5)
+			o := mcts.OptsV5
+			o.Straight = straightOpponents(req)
+			return mcts.SelectMove(ctx, board, coordsToPoints(req.Board.Hazards), rootIdx, &o)
+		},
+	},
+	"v5p": {
+		Description: "MCTS with multiplayer, simultaneous move, Decoupled-UCT, heuristic leaf scores, early stop, pondering",
+		Info: BattlesnakeInfoResponse{
+			APIVersion: "1",
+			Author:     "corverroos",
+			Color:      "#CDD7B6",
+			Head:       "villain",
+			Tail:       "rocket",
+			Meta:       mcts.OptsV5P,
+		},
+		End: func(ctx context.Context, req GameRequest) error {
+			endPonder(req)
+			log.Printf("Early stops v5p: %s\n", v5pStops)
+			return nil
+		},
+		Move: func(ctx context.Context, req GameRequest) (string, error) {
+			board, rootIdx := gameReqToBoard(req)
+			o := mcts.OptsV5P
+			o.Straight = straightOpponents(req)
+			move, stop, err := getPonder(req).SelectMoveStop(ctx, board, coordsToPoints(req.Board.Hazards), rootIdx, &o)
+			v5pStops.add(stop)
+			return move, err
+		},
+	},
+	"v5o": {
+		Description: "MCTS with multiplayer, simultaneous move, Decoupled-UCT, heuristic leaf scores, opponent model",
+		Info: BattlesnakeInfoResponse{
+			APIVersion: "1",
+			Author:     "corverroos",
+			Color:      "#B6CDD7",
+			Head:       "villain",
+			Tail:       "rocket",
+			Meta:       mcts.OptsV5O,
+		},
+		End: endOpponents,
+		Move: func(ctx context.Context, req GameRequest) (string, error) {
+			board, rootIdx := gameReqToBoard(req)
+			o := mcts.OptsV5O
+			o.Opponents = observeOpponents(req, board)
+			o.Straight = straightOpponents(req)
+			return mcts.SelectMove(ctx, board, coordsToPoints(req.Board.Hazards), rootIdx, &o)
+		},
+	},
+	"mx5o": {
+		Description: "Minimax Tree Search, expectimax over opponent model",
+		Info: BattlesnakeInfoResponse{
+			APIVersion: "1",
+			Color:      "#d3d3ef",
+			Head:       "snow-worm",
+			Tail:       "block-bum",
+			Meta:       mcts.OptsV5,
+		},
+		End: endOpponents,
+		Move: func(ctx context.Context, req GameRequest) (string, error) {
+			board, rootIdx := gameReqToBoard(req)
+			o := mcts.OptsV5
+			o.MxBackup = mcts.BackupExpectimax
+			o.Opponents = observeOpponents(req, board)
+			o.Straight = straightOpponents(req)
+			return mcts.SelectMx(ctx, board, coordsToPoints(req.Board.Hazards), rootIdx, &o)
 		},
 	},
 }
 
-var (
-	fmx0 = heur.Factors{
-		Control: 0.01,
-		Length:  0.5,
-		Hunger:  -0.001,
-		Starve:  -0.9,
+// opponents is the opponent model shared by all games, profiles are persisted to
+// the OPP_PROFILES file if set.
+var opponents = opp.NewModel()
+
+// observeOpponents updates the opponent model with the request board and returns a snapshot
+// of the game's move priors for the search.
+func observeOpponents(req GameRequest, b *rules.BoardState) mcts.Policy {
+	names := make(map[string]string)
+	for _, s := range req.Board.Snakes {
+		names[s.ID] = s.Name
 	}
-	fmx1 = heur.Factors{
-		Control: 0.1,
-		Length:  0.3,
-		Hunger:  -0.02,
-		Starve:  -0.8,
+
+	key := req.Game.ID + req.You.ID
+	opponents.Observe(key, b, names)
+
+	return opponents.Game(key)
+}
+
+// endOpponents discards the game's opponent model state and saves the profiles.
+func endOpponents(_ context.Context, req GameRequest) error {
+	opponents.End(req.Game.ID + req.You.ID)
+
+	path := os.Getenv("OPP_PROFILES")
+	if path == "" {
+		return nil
 	}
-	fmx2 = heur.Factors{
-		Control: 0.1,
-		Length:  0.3,
-		Hunger:  -0.001,
-		Starve:  -0.9,
+
+	// Write a unique temporary file and replace the profiles atomically, games end concurrently.
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
 	}
-	fmx3 = heur.Factors{
-		Control: 0.05,
-		Length:  0.35,
-		Hunger:  -0.001,
-		Starve:  -0.9,
+
+	if err := opponents.Save(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
 	}
-)
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// loadOpponents loads the opponent profiles from the OPP_PROFILES file if it exists.
+func loadOpponents() {
+	path := os.Getenv("OPP_PROFILES")
+	if path == "" {
+		return
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		log.Printf("ERROR: load opponent profiles: %v\n", err)
+		return
+	}
+	defer f.Close()
+
+	if err := opponents.Load(f); err != nil {
+		log.Printf("ERROR: load opponent profiles: %v\n", err)
+	}
+}
+
+// loadTuned adds the "tuned" snake searching with the options of the TUNED_OPTS file
+// if set, e.g. the output of perf tune.
+func loadTuned() {
+	path := os.Getenv("TUNED_OPTS")
+	if path == "" {
+		return
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("ERROR: load tuned options: %v\n", err)
+		return
+	}
+
+	var o mcts.Opts
+	if err := json.Unmarshal(b, &o); err != nil {
+		log.Printf("ERROR: load tuned options: %v\n", err)
+		return
+	}
+
+	description, search := "MCTS with tuned options", mcts.SelectMove
+	if o.IsMinimax() {
+		description, search = "Minimax alpha-beta with tuned options", mcts.SelectMinimax
+	}
+
+	snakes["tuned"] = snake{
+		Description: description,
+		Info: BattlesnakeInfoResponse{
+			APIVersion: "1",
+			Author:     "corverroos",
+			Color:      "#CDD7B6",
+			Head:       "villain",
+			Tail:       "rocket",
+			Meta:       o,
+		},
+		Move: func(ctx context.Context, req GameRequest) (string, error) {
+			board, rootIdx := gameReqToBoard(req)
+			o := o
+			o.Straight = straightOpponents(req)
+			return search(ctx, board, coordsToPoints(req.Board.Hazards), rootIdx, &o)
+		},
+	}
+}
+
+// v5pStops counts the early stop rules of the v5p searches.
+var v5pStops = newStopCounter()
+
+// stopCounter counts the searches of a snake by early stop rule, safe for concurrent use.
+type stopCounter struct {
+	searches int64
+	rules    map[string]*int64
+}
+
+func newStopCounter() *stopCounter {
+	c := &stopCounter{rules: make(map[string]*int64)}
+	for _, rule := range mcts.StopRules {
+		c.rules[rule] = new(int64)
+	}
+	return c
+}
+
+// add counts a search and its early stop rule, empty if it searched for the whole budget.
+func (c *stopCounter) add(rule string) {
+	atomic.AddInt64(&c.searches, 1)
+	if n, ok := c.rules[rule]; ok {
+		atomic.AddInt64(n, 1)
+	}
+}
+
+func (c *stopCounter) String() string {
+	res := []string{fmt.Sprintf("searches=%d", atomic.LoadInt64(&c.searches))}
+	for _, rule := range mcts.StopRules {
+		res = append(res, fmt.Sprintf("%s=%d", rule, atomic.LoadInt64(c.rules[rule])))
+	}
+	return strings.Join(res, " ")
+}
+
+// ponderExpiry is the time after its last move that a pondering session is stopped
+// and removed, since games don't always end with a request.
+const ponderExpiry = time.Minute
+
+// ponders are the pondering sessions and their last move times by game and snake.
+var ponders = struct {
+	sync.Mutex
+	m map[string]*ponder
+}{m: make(map[string]*ponder)}
+
+type ponder struct {
+	*mcts.Ponder
+	last time.Time
+}
+
+// getPonder returns the pondering session of the game and snake, expiring idle sessions.
+func getPonder(req GameRequest) *mcts.Ponder {
+	expirePonders(time.Now())
+
+	ponders.Lock()
+	defer ponders.Unlock()
+
+	key := req.Game.ID + req.You.ID
+	p, ok := ponders.m[key]
+	if !ok {
+		p = &ponder{Ponder: new(mcts.Ponder)}
+		ponders.m[key] = p
+	}
+	p.last = time.Now()
+	return p.Ponder
+}
+
+// expirePonders stops and removes the pondering sessions without a move since the expiry.
+func expirePonders(now time.Time) {
+	var expired []*ponder
+	ponders.Lock()
+	for key, p := range ponders.m {
+		if now.Sub(p.last) > ponderExpiry {
+			expired = append(expired, p)
+			delete(ponders.m, key)
+		}
+	}
+	ponders.Unlock()
+
+	for _, p := range expired {
+		p.Stop()
+	}
+}
+
+// endPonder stops and removes the pondering session of the game and snake and logs its reuses.
+func endPonder(req GameRequest) {
+	ponders.Lock()
+	key := req.Game.ID + req.You.ID
+	p, ok := ponders.m[key]
+	delete(ponders.m, key)
+	ponders.Unlock()
+
+	if ok {
+		p.Stop()
+		hits, misses := p.Reuses()
+		log.Printf("Ponder %s: reused=%d missed=%d\n", req.Game.ID, hits, misses)
+	}
+}
 
 func init() {
 	for _, s := range snakes {
@@ -257,24 +514,3 @@ var ballWeights = weights{
 	FoodFull:     -100,
 	HungryHealth: 10,
 }
-
-func mxDepth(b *rules.BoardState) int {
-	var l int
-	for i := 0; i < len(b.Snakes); i++ {
-		if b.Snakes[i].EliminatedCause == "" {
-			l++
-		}
-	}
-	switch l {
-	case 1:
-		return 10
-	case 2:
-		return 4
-	case 3:
-		return 2
-	case 4:
-		return 2
-	default:
-		return 1
-	}
-}
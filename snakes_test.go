@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/corverroos/bsnake/mcts"
+)
+
+func TestStopCounter(t *testing.T) {
+	c := newStopCounter()
+	c.add("")
+	c.add(mcts.StopSingleMove)
+	c.add(mcts.StopSingleMove)
+	c.add(mcts.StopProven)
+	require.Equal(t, "searches=4 single_move=2 visit_lead=0 proven=1", c.String())
+}
+
+func TestExpirePonders(t *testing.T) {
+	idle := GameRequest{Game: Game{ID: "idle"}, You: Battlesnake{ID: "a"}}
+	active := GameRequest{Game: Game{ID: "active"}, You: Battlesnake{ID: "a"}}
+	defer endPonder(active)
+
+	p := getPonder(idle)
+	require.True(t, p == getPonder(idle))
+	getPonder(active)
+
+	ponders.Lock()
+	ponders.m[idle.Game.ID+idle.You.ID].last = time.Now().Add(-ponderExpiry - time.Second)
+	ponders.Unlock()
+
+	expirePonders(time.Now())
+	require.NotContains(t, ponders.m, idle.Game.ID+idle.You.ID)
+	require.Contains(t, ponders.m, active.Game.ID+active.You.ID)
+
+	// A new session after expiry.
+	require.True(t, p != getPonder(idle))
+	endPonder(idle)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// repeatTimeouts is the number of timeouts within the last timeoutWindow turns after
+// which an opponent is modelled as timing out again, moving straight.
+const (
+	repeatTimeouts = 2
+	timeoutWindow  = 10
+)
+
+// timeouts are the turns of recent opponent timeouts by game and snake, then by opponent ID.
+var timeouts = struct {
+	sync.Mutex
+	m map[string]map[string][]int
+}{m: make(map[string]map[string][]int)}
+
+// timedOut returns true if the snake's previous move timed out.
+func timedOut(s Battlesnake) bool {
+	return fmt.Sprint(s.Latency) == "0"
+}
+
+// observeTimeouts records the opponent timeouts of the game and snake from the request
+// latencies, forgetting timeouts older than the window.
+func observeTimeouts(req GameRequest) {
+	if req.Turn == 0 {
+		// No previous move
+		return
+	}
+
+	timeouts.Lock()
+	defer timeouts.Unlock()
+
+	key := req.Game.ID + req.You.ID
+	turns, ok := timeouts.m[key]
+	if !ok {
+		turns = make(map[string][]int)
+		timeouts.m[key] = turns
+	}
+
+	for _, s := range req.Board.Snakes {
+		if s.ID != req.You.ID && timedOut(s) {
+			// The latency is of the previous move.
+			turns[s.ID] = append(turns[s.ID], req.Turn-1)
+		}
+	}
+
+	for id, ts := range turns {
+		for len(ts) > 0 && ts[0] <= req.Turn-1-timeoutWindow {
+			ts = ts[1:]
+		}
+		if len(ts) == 0 {
+			delete(turns, id)
+		} else {
+			turns[id] = ts
+		}
+	}
+}
+
+// straightOpponents returns the IDs of opponents that repeatedly timed out recently in
+// the game, which the engine moves straight, see mcts.Opts.Straight.
+func straightOpponents(req GameRequest) map[string]bool {
+	timeouts.Lock()
+	defer timeouts.Unlock()
+
+	res := make(map[string]bool)
+	for id, ts := range timeouts.m[req.Game.ID+req.You.ID] {
+		if len(ts) >= repeatTimeouts {
+			res[id] = true
+		}
+	}
+	return res
+}
+
+// endTimeouts removes the opponent timeouts of the game and snake.
+func endTimeouts(req GameRequest) {
+	timeouts.Lock()
+	defer timeouts.Unlock()
+
+	delete(timeouts.m, req.Game.ID+req.You.ID)
+}
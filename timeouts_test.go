@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeouts(t *testing.T) {
+	tests := []struct {
+		Name     string
+		TimedOut []string // Opponents that timed out the previous move, by turn from 1.
+		Straight map[string]bool
+	}{
+		{
+			Name:     "none",
+			TimedOut: []string{"", "", ""},
+			Straight: map[string]bool{},
+		},
+		{
+			Name:     "once",
+			TimedOut: []string{"", "b", ""},
+			Straight: map[string]bool{},
+		},
+		{
+			Name:     "threshold",
+			TimedOut: []string{"b", "", "b"},
+			Straight: map[string]bool{"b": true},
+		},
+		{
+			Name:     "both",
+			TimedOut: []string{"bc", "c", "b"},
+			Straight: map[string]bool{"b": true, "c": true},
+		},
+		{
+			Name:     "self ignored",
+			TimedOut: []string{"a", "a", "a"},
+			Straight: map[string]bool{},
+		},
+		{
+			Name:     "within window",
+			TimedOut: []string{"b", "", "", "", "", "", "", "", "", "b"},
+			Straight: map[string]bool{"b": true},
+		},
+		{
+			Name:     "decayed",
+			TimedOut: []string{"b", "", "", "", "", "", "", "", "", "", "b"},
+			Straight: map[string]bool{},
+		},
+		{
+			Name:     "decayed then repeated",
+			TimedOut: []string{"b", "", "", "", "", "", "", "", "", "", "b", "b"},
+			Straight: map[string]bool{"b": true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			req := GameRequest{Game: Game{ID: test.Name}, You: Battlesnake{ID: "a"}}
+			defer endTimeouts(req)
+
+			for turn := 0; turn <= len(test.TimedOut); turn++ {
+				req.Turn = turn
+				req.Board.Snakes = nil
+				for _, id := range []string{"a", "b", "c"} {
+					s := Battlesnake{ID: id, Latency: "120"}
+					if turn > 0 && strings.Contains(test.TimedOut[turn-1], id) {
+						s.Latency = "0"
+					}
+					req.Board.Snakes = append(req.Board.Snakes, s)
+				}
+				observeTimeouts(req)
+			}
+
+			require.Equal(t, test.Straight, straightOpponents(req))
+
+			endTimeouts(req)
+			require.Empty(t, straightOpponents(req))
+			require.NotContains(t, timeouts.m, req.Game.ID+req.You.ID)
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package tournament
+
+import (
+	"math"
+	"sort"
+)
+
+// eloScale converts natural log odds to Elo.
+var eloScale = 400 / math.Ln10
+
+// Pair is an ordered engine pair, A < B.
+type Pair struct {
+	A, B string
+}
+
+// newPair returns the ordered pair of the engines and true if they were swapped.
+func newPair(a, b string) (Pair, bool) {
+	if a > b {
+		return Pair{A: b, B: a}, true
+	}
+	return Pair{A: a, B: b}, false
+}
+
+// Score is the wins, draws and losses of one engine against another.
+type Score struct {
+	W, D, L int
+}
+
+// Add returns the sum of the scores.
+func (s Score) Add(o Score) Score {
+	return Score{W: s.W + o.W, D: s.D + o.D, L: s.L + o.L}
+}
+
+// Flip returns the score from the opponent's perspective.
+func (s Score) Flip() Score {
+	return Score{W: s.L, D: s.D, L: s.W}
+}
+
+// N returns the number of games.
+func (s Score) N() int {
+	return s.W + s.D + s.L
+}
+
+// Mean returns the average points per game, 1 for a win and 0.5 for a draw.
+func (s Score) Mean() float64 {
+	if s.N() == 0 {
+		return 0.5
+	}
+	return (float64(s.W) + float64(s.D)/2) / float64(s.N())
+}
+
+// Elo returns the Elo difference implied by the score and its 95% confidence interval.
+func (s Score) Elo() (float64, float64) {
+	n := float64(s.N())
+	if n == 0 {
+		return 0, math.Inf(1)
+	}
+
+	mean := s.Mean()
+	variance := (float64(s.W)*sq(1-mean) + float64(s.D)*sq(0.5-mean) + float64(s.L)*sq(mean)) / n
+
+	elo := func(p float64) float64 {
+		p = math.Min(math.Max(p, 1e-6), 1-1e-6)
+		return -400 * math.Log10(1/p-1)
+	}
+
+	margin := 1.96 * math.Sqrt(variance/n)
+
+	return elo(mean), (elo(mean+margin) - elo(mean-margin)) / 2
+}
+
+// Rating is the Elo rating of an engine relative to the average engine.
+type Rating struct {
+	Name  string
+	Elo   float64
+	CI    float64 // 95% confidence interval
+	Games int
+}
+
+// Ratings returns the Bradley-Terry maximum likelihood Elo ratings of the engines, ordered
+// from highest. A virtual draw between each pair that played keeps ratings finite.
+func Ratings(scores map[Pair]Score) []Rating {
+	var names []string
+	seen := make(map[string]bool)
+	for p := range scores {
+		for _, name := range []string{p.A, p.B} {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	idx := make(map[string]int)
+	for i, name := range names {
+		idx[name] = i
+	}
+
+	l := len(names)
+	wins := make([]float64, l)
+	games := make([][]float64, l)
+	for i := range games {
+		games[i] = make([]float64, l)
+	}
+	for p, s := range scores {
+		a, b := idx[p.A], idx[p.B]
+		n := float64(s.N()) + 1
+		games[a][b] += n
+		games[b][a] += n
+		wins[a] += float64(s.W) + float64(s.D)/2 + 0.5
+		wins[b] += float64(s.L) + float64(s.D)/2 + 0.5
+	}
+
+	// Minorization-maximization iterations of the strengths.
+	strength := make([]float64, l)
+	for i := range strength {
+		strength[i] = 1
+	}
+	for iter := 0; iter < 1000; iter++ {
+		var delta float64
+		next := make([]float64, l)
+		for i := range names {
+			var denom float64
+			for j := range names {
+				if games[i][j] > 0 {
+					denom += games[i][j] / (strength[i] + strength[j])
+				}
+			}
+			next[i] = wins[i] / denom
+			delta = math.Max(delta, math.Abs(next[i]-strength[i]))
+		}
+		strength = next
+		if delta < 1e-9 {
+			break
+		}
+	}
+
+	var mean float64
+	for _, s := range strength {
+		mean += math.Log(s) / float64(l)
+	}
+
+	res := make([]Rating, l)
+	for i, name := range names {
+		var info float64
+		var n int
+		for j := range names {
+			p := strength[i] / (strength[i] + strength[j])
+			info += games[i][j] * p * (1 - p)
+			if games[i][j] > 0 {
+				n += int(games[i][j]) - 1
+			}
+		}
+		res[i] = Rating{
+			Name:  name,
+			Elo:   eloScale * (math.Log(strength[i]) - mean),
+			CI:    1.96 * eloScale / math.Sqrt(info),
+			Games: n,
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Elo > res[j].Elo
+	})
+
+	return res
+}
+
+// Decision is the outcome of a sequential probability ratio test.
+type Decision int
+
+const (
+	// Continue indicates more games are required.
+	Continue Decision = 0
+
+	// AcceptH0 indicates the candidate is not better than Elo0.
+	AcceptH0 Decision = 1
+
+	// AcceptH1 indicates the candidate is better than Elo1.
+	AcceptH1 Decision = 2
+)
+
+func (d Decision) String() string {
+	switch d {
+	case Continue:
+		return "continue"
+	case AcceptH0:
+		return "H0"
+	case AcceptH1:
+		return "H1"
+	default:
+		return "unknown"
+	}
+}
+
+// SPRT is a sequential probability ratio test of the hypotheses H0: elo <= Elo0
+// and H1: elo >= Elo1 at false positive rate Alpha and false negative rate Beta.
+// It uses the normal approximation of the trinomial (win/draw/loss) likelihood ratio.
+type SPRT struct {
+	Elo0  float64
+	Elo1  float64
+	Alpha float64 // Defaults to 0.05.
+	Beta  float64 // Defaults to 0.05.
+}
+
+// Bounds returns the lower and upper log likelihood ratio bounds.
+func (s SPRT) Bounds() (float64, float64) {
+	alpha, beta := s.Alpha, s.Beta
+	if alpha == 0 {
+		alpha = 0.05
+	}
+	if beta == 0 {
+		beta = 0.05
+	}
+
+	return math.Log(beta / (1 - alpha)), math.Log((1 - beta) / alpha)
+}
+
+// LLR returns the log likelihood ratio of H1 versus H0 given the candidate's score.
+func (s SPRT) LLR(score Score) float64 {
+	n := float64(score.N())
+	if score.W == 0 || score.L == 0 {
+		// The variance is degenerate, add a virtual win and loss.
+		score = score.Add(Score{W: 1, L: 1})
+		n += 2
+	}
+
+	mean := score.Mean()
+	variance := (float64(score.W)*sq(1-mean) + float64(score.D)*sq(0.5-mean) + float64(score.L)*sq(mean)) / n
+
+	s0 := 1 / (1 + math.Pow(10, -s.Elo0/400))
+	s1 := 1 / (1 + math.Pow(10, -s.Elo1/400))
+
+	return n * (s1 - s0) * (2*mean - s0 - s1) / (2 * variance)
+}
+
+// Decide returns the test decision given the candidate's score.
+func (s SPRT) Decide(score Score) Decision {
+	llr := s.LLR(score)
+	lower, upper := s.Bounds()
+
+	if llr >= upper {
+		return AcceptH1
+	} else if llr <= lower {
+		return AcceptH0
+	}
+
+	return Continue
+}
+
+func sq(f float64) float64 {
+	return f * f
+}
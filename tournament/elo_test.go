@@ -0,0 +1,47 @@
+package tournament
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoreElo(t *testing.T) {
+	elo, ci := Score{W: 75, L: 25}.Elo()
+	require.InDelta(t, 190.8, elo, 0.1)
+	require.InDelta(t, 81.2, ci, 0.1)
+
+	elo, _ = Score{W: 10, D: 20, L: 10}.Elo()
+	require.InDelta(t, 0, elo, 1e-9)
+}
+
+func TestRatings(t *testing.T) {
+	ratings := Ratings(map[Pair]Score{
+		{A: "a", B: "b"}: {W: 750, L: 250},
+		{A: "b", B: "c"}: {W: 500, D: 100, L: 400},
+	})
+
+	require.Len(t, ratings, 3)
+	require.Equal(t, "a", ratings[0].Name)
+	require.Equal(t, "b", ratings[1].Name)
+	require.Equal(t, "c", ratings[2].Name)
+	require.Equal(t, 2000, ratings[1].Games)
+
+	require.InDelta(t, 190.8, ratings[0].Elo-ratings[1].Elo, 1)
+	require.InDelta(t, 34.9, ratings[1].Elo-ratings[2].Elo, 1)
+	require.InDelta(t, 0, ratings[0].Elo+ratings[1].Elo+ratings[2].Elo, 1e-6)
+	require.Less(t, ratings[1].CI, ratings[0].CI) // b played the most games
+}
+
+func TestSPRT(t *testing.T) {
+	s := SPRT{Elo0: 0, Elo1: 50}
+
+	lower, upper := s.Bounds()
+	require.InDelta(t, -2.94, lower, 0.01)
+	require.InDelta(t, 2.94, upper, 0.01)
+
+	require.Equal(t, Continue, s.Decide(Score{W: 6, L: 4}))
+	require.Equal(t, AcceptH1, s.Decide(Score{W: 120, L: 60}))
+	require.Equal(t, AcceptH0, s.Decide(Score{W: 150, D: 20, L: 150}))
+	require.Equal(t, AcceptH1, s.Decide(Score{W: 20}))
+}
@@ -0,0 +1,127 @@
+package tournament
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/corverroos/bsnake/arena"
+)
+
+// Record is the persisted outcome of a tournament game.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Seed    int64     `json:"seed"`
+	Engines []string  `json:"engines"` // Engine names by snake index
+	Deaths  []int     `json:"deaths"`  // Turn each snake was eliminated, -1 if alive
+	Turns   int       `json:"turns"`
+}
+
+func newRecord(r arena.Result) Record {
+	return Record{
+		Time:    time.Now(),
+		Type:    r.Type,
+		Seed:    r.Seed,
+		Engines: r.Engines,
+		Deaths:  r.Deaths,
+		Turns:   r.Turns,
+	}
+}
+
+// History is the persisted record of tournament games.
+type History struct {
+	Records []Record `json:"records"`
+}
+
+// LoadHistory returns the history stored in the JSON file or an empty history if it doesn't exist.
+func LoadHistory(path string) (History, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return History{}, nil
+	} else if err != nil {
+		return History{}, err
+	}
+
+	var h History
+	if err := json.Unmarshal(b, &h); err != nil {
+		return History{}, err
+	}
+
+	return h, nil
+}
+
+// Save writes the history to the JSON file, replacing it atomically.
+func (h History) Save(path string) error {
+	b, err := json.MarshalIndent(h, "", " ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// nextGame returns the index of the first game after the recorded games of the options
+// seeded from seed, so that resumed tournaments don't replay recorded seeds.
+func (h History) nextGame(seed int64) int {
+	var res int
+	for _, r := range h.Records {
+		if i := int(r.Seed-seed) + 1; i > res {
+			res = i
+		}
+	}
+	return res
+}
+
+// Scores returns the pairwise scores of all engine pairs in the history, see Pair.
+func (h History) Scores() map[Pair]Score {
+	res := make(map[Pair]Score)
+	for _, r := range h.Records {
+		addScores(res, r)
+	}
+	return res
+}
+
+// addScores adds the pairwise outcomes of the record's snakes to the scores. A snake
+// beats another if it outlived it, snakes eliminated on the same turn draw.
+func addScores(scores map[Pair]Score, r Record) {
+	for i := range r.Engines {
+		for j := i + 1; j < len(r.Engines); j++ {
+			a, b := r.Engines[i], r.Engines[j]
+			if a == b {
+				continue
+			}
+
+			var s Score
+			switch outlived(r.Deaths[i], r.Deaths[j]) {
+			case 1:
+				s.W++
+			case -1:
+				s.L++
+			default:
+				s.D++
+			}
+
+			p, flip := newPair(a, b)
+			if flip {
+				s.W, s.L = s.L, s.W
+			}
+			scores[p] = scores[p].Add(s)
+		}
+	}
+}
+
+// outlived returns 1 if death a is after b, -1 if before and 0 if equal. A death of -1 is alive.
+func outlived(a, b int) int {
+	if a == b {
+		return 0
+	} else if a == -1 || (b != -1 && a > b) {
+		return 1
+	}
+	return -1
+}
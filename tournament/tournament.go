@@ -0,0 +1,150 @@
+// Package tournament plays round-robin and gauntlet tournaments between arena engines,
+// rating them by Elo and stopping early by SPRT.
+package tournament
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/corverroos/bsnake/arena"
+)
+
+// Mode defines the pairings of a tournament.
+type Mode string
+
+const (
+	// RoundRobin plays every engine against every other engine.
+	RoundRobin Mode = "roundrobin"
+
+	// Gauntlet plays the candidate, the first engine, against each of the other engines.
+	Gauntlet Mode = "gauntlet"
+)
+
+// Options configure a tournament.
+type Options struct {
+	Mode    Mode
+	Engines []string      // Participants, the candidate first in a gauntlet.
+	Rounds  int           // Maximum rounds, each round plays every pairing once, defaults to 1.
+	Arena   arena.Options // Board, game type, seed and timeout options, engines are ignored.
+	SPRT    *SPRT         // Stops a gauntlet once the candidate's score against the others is decided.
+	Path    string        // JSON history file, ratings include previous runs.
+}
+
+// Summary is the outcome of a tournament.
+type Summary struct {
+	Games    int            // Games played in this run
+	Ratings  []Rating       // Ratings over the full history
+	Scores   map[Pair]Score // Pairwise scores over the full history
+	Decision Decision       // SPRT decision of this run's candidate score
+	LLR      float64        // SPRT log likelihood ratio of this run's candidate score
+	Score    Score          // Candidate score of this run in a gauntlet
+}
+
+// pairings returns the 2-player engine pairings of the tournament mode.
+func (o Options) pairings() ([][2]string, error) {
+	if len(o.Engines) < 2 {
+		return nil, fmt.Errorf("tournament requires at least two engines")
+	}
+
+	var res [][2]string
+	switch o.Mode {
+	case RoundRobin:
+		for i := range o.Engines {
+			for j := i + 1; j < len(o.Engines); j++ {
+				res = append(res, [2]string{o.Engines[i], o.Engines[j]})
+			}
+		}
+	case Gauntlet:
+		for _, other := range o.Engines[1:] {
+			res = append(res, [2]string{o.Engines[0], other})
+		}
+	default:
+		return nil, fmt.Errorf("unknown tournament mode: %s", o.Mode)
+	}
+
+	return res, nil
+}
+
+// Run plays the tournament, calling fn with each game result, and returns its summary.
+// The history is saved after each game if a path is set and games resume after its seeds.
+func Run(ctx context.Context, o Options, fn func(arena.Result)) (Summary, error) {
+	pairings, err := o.pairings()
+	if err != nil {
+		return Summary{}, err
+	}
+
+	if o.SPRT != nil && o.Mode != Gauntlet {
+		return Summary{}, fmt.Errorf("sprt requires gauntlet mode")
+	}
+
+	var h History
+	if o.Path != "" {
+		h, err = LoadHistory(o.Path)
+		if err != nil {
+			return Summary{}, err
+		}
+	}
+
+	rounds := o.Rounds
+	if rounds == 0 {
+		rounds = 1
+	}
+
+	var res Summary
+	offset := h.nextGame(o.Arena.Seed)
+
+	for round := 0; round < rounds && res.Decision == Continue; round++ {
+		for _, pair := range pairings {
+			if ctx.Err() != nil {
+				return Summary{}, ctx.Err()
+			}
+
+			// Alternate starting positions between rounds.
+			engines := []string{pair[0], pair[1]}
+			if round%2 == 1 {
+				engines = []string{pair[1], pair[0]}
+			}
+
+			r := arena.Play(ctx, o.Arena.Game(offset+res.Games, engines))
+			res.Games++
+			if fn != nil {
+				fn(r)
+			}
+			if r.Error != "" {
+				return Summary{}, fmt.Errorf("game %s: %s", r.ID, r.Error)
+			}
+
+			rec := newRecord(r)
+			h.Records = append(h.Records, rec)
+			if o.Path != "" {
+				if err := h.Save(o.Path); err != nil {
+					return Summary{}, err
+				}
+			}
+
+			if o.Mode == Gauntlet {
+				scores := make(map[Pair]Score)
+				addScores(scores, rec)
+				for p, s := range scores {
+					if p.B == o.Engines[0] {
+						s = s.Flip()
+					}
+					res.Score = res.Score.Add(s)
+				}
+			}
+
+			if o.SPRT != nil {
+				res.LLR = o.SPRT.LLR(res.Score)
+				res.Decision = o.SPRT.Decide(res.Score)
+				if res.Decision != Continue {
+					break
+				}
+			}
+		}
+	}
+
+	res.Scores = h.Scores()
+	res.Ratings = Ratings(res.Scores)
+
+	return res, nil
+}
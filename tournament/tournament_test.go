@@ -0,0 +1,92 @@
+package tournament
+
+import (
+	"context"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/corverroos/bsnake/arena"
+)
+
+func init() {
+	arena.Register("up", func(*rand.Rand) arena.Engine {
+		return arena.EngineFunc(func(context.Context, arena.Turn) (string, error) {
+			return "up", nil
+		})
+	})
+}
+
+func TestGauntletSPRT(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	o := Options{
+		Mode:    Gauntlet,
+		Engines: []string{"random", "up"},
+		Rounds:  100,
+		Arena:   arena.Options{Width: 7, Height: 7, Seed: 1},
+		SPRT:    &SPRT{Elo0: 0, Elo1: 50},
+		Path:    path,
+	}
+
+	res, err := Run(context.Background(), o, nil)
+	jtest.RequireNil(t, err)
+	require.Equal(t, AcceptH1, res.Decision)
+	require.Less(t, res.Games, 100)
+	require.Equal(t, res.Games, res.Score.N())
+	require.Equal(t, "random", res.Ratings[0].Name)
+
+	// History is persisted between runs.
+	h, err := LoadHistory(path)
+	jtest.RequireNil(t, err)
+	require.Len(t, h.Records, res.Games)
+
+	o.SPRT = nil
+	o.Rounds = 2
+	res2, err := Run(context.Background(), o, nil)
+	jtest.RequireNil(t, err)
+	require.Equal(t, 2, res2.Games)
+	require.Equal(t, res.Games+2, res2.Scores[Pair{A: "random", B: "up"}].N())
+
+	// Resumed games don't replay recorded seeds.
+	h, err = LoadHistory(path)
+	jtest.RequireNil(t, err)
+	seeds := make(map[int64]bool)
+	for _, r := range h.Records {
+		require.False(t, seeds[r.Seed], r.Seed)
+		seeds[r.Seed] = true
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	o := Options{
+		Mode:    RoundRobin,
+		Engines: []string{"random", "up", "v0"},
+		Arena:   arena.Options{Width: 7, Height: 7},
+	}
+
+	_, err := Run(context.Background(), o, nil)
+	require.EqualError(t, err, "game game-1: unknown engine: v0")
+
+	o.Engines = []string{"random", "up", "random2"}
+	arena.Register("random2", func(*rand.Rand) arena.Engine {
+		return arena.EngineFunc(func(ctx context.Context, t arena.Turn) (string, error) {
+			return "down", nil
+		})
+	})
+
+	var played [][]string
+	res, err := Run(context.Background(), o, func(r arena.Result) {
+		played = append(played, r.Engines)
+	})
+	jtest.RequireNil(t, err)
+	require.Equal(t, [][]string{{"random", "up"}, {"random", "random2"}, {"up", "random2"}}, played)
+	require.Len(t, res.Ratings, 3)
+
+	o.SPRT = &SPRT{}
+	_, err = Run(context.Background(), o, nil)
+	require.EqualError(t, err, "sprt requires gauntlet mode")
+}
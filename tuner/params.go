@@ -0,0 +1,133 @@
+package tuner
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/corverroos/bsnake/heur"
+	"github.com/corverroos/bsnake/mcts"
+)
+
+// Param is a tuned numeric field of mcts.Opts, nested heur.Factors fields are
+// prefixed by "HeurFactors.".
+type Param struct {
+	Name string  `json:"name"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+}
+
+// DefaultParams are the heuristic factors and search options tuned by default.
+var DefaultParams = []Param{
+	{Name: "HeurFactors.Control", Min: 0, Max: 0.2},
+	{Name: "HeurFactors.Length", Min: 0, Max: 1},
+	{Name: "HeurFactors.Boxed", Min: -1, Max: 0},
+	{Name: "HeurFactors.Walls", Min: -0.1, Max: 0.1},
+	{Name: "HeurFactors.Hunger", Min: -0.05, Max: 0},
+	{Name: "HeurFactors.Starve", Min: -1, Max: 0},
+	{Name: "HeurFactors.Health", Min: 0, Max: 1},
+	{Name: "UCB1_C", Min: 0.5, Max: 8},
+	{Name: "SelectRandom", Min: 0, Max: 50},
+}
+
+// ParamsByName returns the default params by name.
+func ParamsByName(names []string) ([]Param, error) {
+	var res []Param
+	for _, name := range names {
+		var found bool
+		for _, p := range DefaultParams {
+			if p.Name == name {
+				res = append(res, p)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown param: %s", name)
+		}
+	}
+
+	return res, nil
+}
+
+// normalize returns the value mapped to [0,1] of the param's range.
+func (p Param) normalize(v float64) float64 {
+	return math.Max(0, math.Min(1, (v-p.Min)/(p.Max-p.Min)))
+}
+
+// denormalize returns the value of x in [0,1] of the param's range.
+func (p Param) denormalize(x float64) float64 {
+	return p.Min + x*(p.Max-p.Min)
+}
+
+// Get returns the value of the param in the options. Heuristic factor params are an
+// error if the options have no factors, since the engine doesn't use them.
+func Get(o mcts.Opts, name string) (float64, error) {
+	if o.HeurFactors == nil && strings.HasPrefix(name, "HeurFactors.") {
+		return 0, fmt.Errorf("no heuristic factors: %s", name)
+	}
+
+	v, err := field(&o, name)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	default:
+		return float64(v.Int()), nil
+	}
+}
+
+// Set sets the value of the param in the options, rounding integer fields. It copies
+// the heuristic factors before modifying them, since presets share them.
+func Set(o *mcts.Opts, name string, value float64) error {
+	if strings.HasPrefix(name, "HeurFactors.") {
+		f := new(heur.Factors)
+		if o.HeurFactors != nil {
+			*f = *o.HeurFactors
+		}
+		o.HeurFactors = f
+	}
+
+	v, err := field(o, name)
+	if err != nil {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(value)
+	default:
+		v.SetInt(int64(math.Round(value)))
+	}
+
+	return nil
+}
+
+// field returns the numeric field of the options by name.
+func field(o *mcts.Opts, name string) (reflect.Value, error) {
+	v := reflect.ValueOf(o).Elem()
+	for _, part := range strings.Split(name, ".") {
+		if v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("unknown param: %s", name)
+		}
+
+		f, ok := v.Type().FieldByName(part)
+		if !ok || f.PkgPath != "" {
+			return reflect.Value{}, fmt.Errorf("unknown param: %s", name)
+		}
+		v = v.FieldByIndex(f.Index)
+	}
+
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v, nil
+	default:
+		return reflect.Value{}, fmt.Errorf("param not numeric: %s", name)
+	}
+}
@@ -0,0 +1,49 @@
+package tuner
+
+import (
+	"testing"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/corverroos/bsnake/mcts"
+)
+
+func TestParams(t *testing.T) {
+	o := mcts.OptsV5
+
+	v, err := Get(o, "HeurFactors.Length")
+	jtest.RequireNil(t, err)
+	require.Equal(t, 0.4, v)
+
+	jtest.RequireNil(t, Set(&o, "HeurFactors.Length", 0.6))
+	jtest.RequireNil(t, Set(&o, "UCB1_C", 2.5))
+	jtest.RequireNil(t, Set(&o, "MaxPlayout", 12.6))
+	require.Equal(t, 0.6, o.HeurFactors.Length)
+	require.Equal(t, 2.5, o.UCB1_C)
+	require.Equal(t, 13, o.MaxPlayout)
+
+	// The preset's factors are not modified.
+	require.Equal(t, 0.4, mcts.OptsV5.HeurFactors.Length)
+	require.Equal(t, mcts.OptsV5.HeurFactors.Starve, o.HeurFactors.Starve)
+
+	// Nil factors aren't tuned from zero.
+	_, err = Get(mcts.OptsV1, "HeurFactors.Control")
+	require.EqualError(t, err, "no heuristic factors: HeurFactors.Control")
+
+	v, err = Get(mcts.OptsMx1, "HeurFactors.Hunger")
+	jtest.RequireNil(t, err)
+	require.Equal(t, -0.02, v)
+
+	_, err = Get(o, "Unknown")
+	require.EqualError(t, err, "unknown param: Unknown")
+	_, err = Get(o, "HeurFactors.Unknown")
+	require.EqualError(t, err, "unknown param: HeurFactors.Unknown")
+	_, err = Get(o, "Tuned")
+	require.EqualError(t, err, "param not numeric: Tuned")
+	_, err = Get(o, "hazards")
+	require.EqualError(t, err, "unknown param: hazards")
+
+	_, err = ParamsByName([]string{"UCB1_C", "unknown"})
+	require.EqualError(t, err, "unknown param: unknown")
+}
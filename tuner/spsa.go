@@ -0,0 +1,262 @@
+// Package tuner tunes numeric mcts.Opts and heur.Factors parameters by simultaneous
+// perturbation stochastic approximation (SPSA) using arena games as the objective.
+package tuner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+
+	"github.com/corverroos/bsnake/arena"
+	"github.com/corverroos/bsnake/mcts"
+)
+
+// Engine names of the perturbed options registered with the arena.
+const (
+	enginePlus  = "spsa+"
+	engineMinus = "spsa-"
+)
+
+// Options configure a tuning run.
+type Options struct {
+	Base       mcts.Opts                                     // Options of the starting point, tuned params are overridden.
+	Params     []Param                                       // Tuned params, defaults to DefaultParams.
+	Iterations int                                           // SPSA iterations, defaults to 100.
+	Games      int                                           // Games per iteration between the perturbed options, defaults to 2.
+	Rate       float64                                       // Initial step size of normalised params, defaults to 0.02.
+	Perturb    float64                                       // Initial perturbation of normalised params, defaults to 0.1.
+	Arena      arena.Options                                 // Board, game type, seed and worker options, engines are ignored.
+	Path       string                                        // JSON checkpoint file, resumed if it exists.
+	Engine     func(mcts.Opts) func(*rand.Rand) arena.Engine // Engine constructor of the options, defaults to arena.MCTSEngine.
+}
+
+// Checkpoint is the persisted progress of a tuning run.
+type Checkpoint struct {
+	Iteration int                `json:"iteration"` // Completed iterations
+	Values    map[string]float64 `json:"values"`    // Current param values by name
+	Scores    []float64          `json:"scores"`    // Score of the positive versus the negative perturbation by iteration
+}
+
+// LoadCheckpoint returns the checkpoint stored in the JSON file and true or false if it doesn't exist.
+func LoadCheckpoint(path string) (Checkpoint, bool, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Checkpoint{}, false, nil
+	} else if err != nil {
+		return Checkpoint{}, false, err
+	}
+
+	var c Checkpoint
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Checkpoint{}, false, err
+	}
+
+	return c, true, nil
+}
+
+// Save writes the checkpoint to the JSON file, replacing it atomically.
+func (c Checkpoint) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", " ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Run tunes the params, calling fn after each iteration, and returns the base options
+// with the tuned param values.
+func Run(ctx context.Context, o Options, fn func(Checkpoint)) (mcts.Opts, error) {
+	for _, t := range o.Arena.GameTypes {
+		if t == arena.Solo {
+			return mcts.Opts{}, fmt.Errorf("solo games can't compare options")
+		}
+	}
+
+	params := o.Params
+	if len(params) == 0 {
+		params = DefaultParams
+	}
+	iterations := defaultInt(o.Iterations, 100)
+	games := defaultInt(o.Games, 2)
+	rate := defaultFloat(o.Rate, 0.02)
+	perturb := defaultFloat(o.Perturb, 0.1)
+	engine := o.Engine
+	if engine == nil {
+		engine = arena.MCTSEngine
+	}
+
+	c := Checkpoint{Values: make(map[string]float64)}
+	if o.Path != "" {
+		var (
+			ok  bool
+			err error
+		)
+		c, ok, err = LoadCheckpoint(o.Path)
+		if err != nil {
+			return mcts.Opts{}, err
+		} else if !ok {
+			c.Values = make(map[string]float64)
+		}
+	}
+
+	// Normalised params, starting from the checkpoint or the base options.
+	theta := make([]float64, len(params))
+	for i, p := range params {
+		v, ok := c.Values[p.Name]
+		if !ok {
+			var err error
+			v, err = Get(o.Base, p.Name)
+			if err != nil {
+				return mcts.Opts{}, err
+			}
+		}
+		theta[i] = p.normalize(v)
+	}
+
+	// Stability constant of the step size sequence, see Spall 1998.
+	stability := float64(iterations) / 10
+
+	rnd := rand.New(rand.NewSource(o.Arena.Seed))
+	for k := 0; k < c.Iteration; k++ {
+		// Replay the perturbation directions of completed iterations.
+		perturbation(rnd, len(params))
+	}
+
+	for k := c.Iteration; k < iterations; k++ {
+		ak := rate * math.Pow((stability+1)/(float64(k)+1+stability), 0.602)
+		ck := perturb / math.Pow(float64(k)+1, 0.101)
+		delta := perturbation(rnd, len(params))
+
+		plus, minus := make([]float64, len(params)), make([]float64, len(params))
+		for i := range theta {
+			plus[i] = clamp(theta[i] + ck*delta[i])
+			minus[i] = clamp(theta[i] - ck*delta[i])
+		}
+
+		optsPlus, err := apply(o.Base, params, plus)
+		if err != nil {
+			return mcts.Opts{}, err
+		}
+		optsMinus, err := apply(o.Base, params, minus)
+		if err != nil {
+			return mcts.Opts{}, err
+		}
+		arena.Register(enginePlus, engine(optsPlus))
+		arena.Register(engineMinus, engine(optsMinus))
+
+		score, err := play(ctx, o.Arena, games, k)
+		if err != nil {
+			return mcts.Opts{}, err
+		}
+
+		// Gradient ascent of the score, the difference of the objective at the perturbations.
+		for i := range theta {
+			theta[i] = clamp(theta[i] + ak*score/(2*ck)*delta[i])
+		}
+
+		c.Iteration = k + 1
+		c.Scores = append(c.Scores, score)
+		for i, p := range params {
+			c.Values[p.Name] = p.denormalize(theta[i])
+		}
+
+		if o.Path != "" {
+			if err := c.Save(o.Path); err != nil {
+				return mcts.Opts{}, err
+			}
+		}
+		if fn != nil {
+			fn(c)
+		}
+	}
+
+	return apply(o.Base, params, theta)
+}
+
+// play plays the iteration's games between the positive and negative perturbations
+// and returns the average score of the positive perturbation in [-1,1].
+func play(ctx context.Context, o arena.Options, games, k int) (float64, error) {
+	o.Engines = []string{enginePlus, engineMinus}
+	o.Players = 2
+	o.Games = games
+	o.Seed += int64(k * games)
+	o.Skip = nil
+
+	var total float64
+	err := arena.Run(ctx, o, func(r arena.Result) error {
+		if r.Error != "" {
+			return fmt.Errorf("game %s: %s", r.ID, r.Error)
+		}
+
+		// A snake wins if it outlived the other.
+		d0, d1 := r.Deaths[0], r.Deaths[1]
+		if d0 == d1 {
+			return nil
+		}
+
+		s := 1.0
+		if d0 != -1 && (d1 == -1 || d0 < d1) {
+			s = -1
+		}
+		if r.Engines[0] == engineMinus {
+			s = -s
+		}
+		total += s
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return total / float64(games), nil
+}
+
+// apply returns a copy of the base options with the normalised param values.
+func apply(base mcts.Opts, params []Param, theta []float64) (mcts.Opts, error) {
+	for i, p := range params {
+		if err := Set(&base, p.Name, p.denormalize(theta[i])); err != nil {
+			return mcts.Opts{}, err
+		}
+	}
+	return base, nil
+}
+
+// perturbation returns a random Rademacher (±1) perturbation direction.
+func perturbation(rnd *rand.Rand, n int) []float64 {
+	res := make([]float64, n)
+	for i := range res {
+		res[i] = 1
+		if rnd.Intn(2) == 0 {
+			res[i] = -1
+		}
+	}
+	return res
+}
+
+func clamp(x float64) float64 {
+	return math.Max(0, math.Min(1, x))
+}
+
+func defaultInt(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+func defaultFloat(v, def float64) float64 {
+	if v == 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,84 @@
+package tuner
+
+import (
+	"context"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/BattlesnakeOfficial/rules"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/corverroos/bsnake/arena"
+	"github.com/corverroos/bsnake/board"
+	"github.com/corverroos/bsnake/mcts"
+)
+
+// skillEngine plays a random rational move with probability UCB1_C/8, otherwise
+// an irrational move if possible.
+func skillEngine(o mcts.Opts) func(*rand.Rand) arena.Engine {
+	return func(rnd *rand.Rand) arena.Engine {
+		return arena.EngineFunc(func(_ context.Context, t arena.Turn) (string, error) {
+			rational := rnd.Float64() < o.UCB1_C/8
+
+			var moves []string
+			for _, move := range board.Moves {
+				if board.IsRationalMove(t.Board, t.SnakeIdx, move) == rational {
+					moves = append(moves, move)
+				}
+			}
+			if len(moves) == 0 {
+				return rules.MoveUp, nil
+			}
+			return moves[rnd.Intn(len(moves))], nil
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	base := mcts.OptsV5
+	base.UCB1_C = 2
+
+	o := Options{
+		Base:       base,
+		Params:     []Param{{Name: "UCB1_C", Min: 0, Max: 8}, DefaultParams[0]},
+		Iterations: 20,
+		Games:      4,
+		Rate:       0.05,
+		Arena:      arena.Options{Width: 7, Height: 7, Seed: 1, Workers: 2},
+		Engine:     skillEngine,
+	}
+
+	var iterations int
+	res, err := Run(context.Background(), o, func(c Checkpoint) {
+		iterations++
+		require.Len(t, c.Scores, c.Iteration)
+	})
+	jtest.RequireNil(t, err)
+	require.Equal(t, 20, iterations)
+	require.Greater(t, res.UCB1_C, 3.0)
+
+	// Interrupted runs resume from the checkpoint.
+	o.Path = filepath.Join(t.TempDir(), "tune.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = Run(ctx, o, func(c Checkpoint) {
+		if c.Iteration == 10 {
+			cancel()
+		}
+	})
+	jtest.Require(t, context.Canceled, err)
+
+	c, ok, err := LoadCheckpoint(o.Path)
+	jtest.RequireNil(t, err)
+	require.True(t, ok)
+	require.Equal(t, 10, c.Iteration)
+
+	resumed, err := Run(context.Background(), o, nil)
+	jtest.RequireNil(t, err)
+	require.Equal(t, res, resumed)
+
+	o.Arena.GameTypes = []string{arena.Solo}
+	_, err = Run(context.Background(), o, nil)
+	require.EqualError(t, err, "solo games can't compare options")
+}